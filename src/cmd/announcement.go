@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+	"github.com/palantir/stacktrace"
+)
+
+type AnnouncementData struct {
+	ID          int64                    `json:"id"`
+	Kind        usecase.AnnouncementKind `json:"kind"`
+	Title       string                   `json:"title"`
+	Body        string                   `json:"body,omitempty"`
+	Markets     []string                 `json:"markets,omitempty"`
+	StartsAt    int64                    `json:"starts_at"`
+	StartsAtISO string                   `json:"starts_at_iso,omitempty"`
+	EndsAt      int64                    `json:"ends_at,omitempty"`
+	EndsAtISO   string                   `json:"ends_at_iso,omitempty"`
+}
+
+func newAnnouncementData(c echo.Context, announcement usecase.Announcement) AnnouncementData {
+	data := AnnouncementData{
+		ID:      announcement.ID,
+		Kind:    announcement.Kind,
+		Title:   announcement.Title,
+		Body:    announcement.Body,
+		Markets: announcement.Markets,
+	}
+	data.StartsAt, data.StartsAtISO = apiTime(c, announcement.StartsAt)
+	if !announcement.EndsAt.IsZero() {
+		data.EndsAt, data.EndsAtISO = apiTime(c, announcement.EndsAt)
+	}
+	return data
+}
+
+// loadAnnouncements publishes the announcements listed in the JSON file at path in place of the current ones.
+// Admins manage announcements through the file rather than the API, which has no authentication to restrict
+// it to them.
+func (ex *Exchange) loadAnnouncements(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return stacktrace.Propagate(err, "loadAnnouncements: failed to read %s", path)
+	}
+	var announcements []usecase.Announcement
+	if err := json.Unmarshal(raw, &announcements); err != nil {
+		return stacktrace.Propagate(err, "loadAnnouncements: failed to parse %s", path)
+	}
+	if _, err := ex.announcements.Replace(announcements); err != nil {
+		return stacktrace.Propagate(err, "loadAnnouncements: invalid announcements in %s", path)
+	}
+	return nil
+}
+
+// handleGetAnnouncements lists the operational notices that haven't ended, soonest to start first.
+func (ex *Exchange) handleGetAnnouncements(c echo.Context) error {
+	announcements := []AnnouncementData{}
+	for _, announcement := range ex.announcements.Current() {
+		announcements = append(announcements, newAnnouncementData(c, announcement))
+	}
+	return c.JSON(200, map[string]any{
+		"announcements": announcements,
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadAnnouncements(t *testing.T) {
+	Convey("When admins publish announcements through the announcements file", t, func() {
+		e := echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(e.Group("/api/v1"))
+		path := filepath.Join(t.TempDir(), "announcements.json")
+
+		current := func() []AnnouncementData {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/announcements", nil))
+			var res struct {
+				Announcements []AnnouncementData `json:"announcements"`
+			}
+			So(json.Unmarshal(rec.Body.Bytes(), &res), ShouldBeNil)
+			return res.Announcements
+		}
+
+		So(os.WriteFile(path, []byte(`[
+			{"kind": "MAINTENANCE", "title": "Scheduled maintenance", "starts_at": "2099-01-01T00:00:00Z", "ends_at": "2099-01-01T01:00:00Z"},
+			{"kind": "LISTING", "title": "ETH/USDT is listed", "markets": ["ETHUSDT"]}
+		]`), 0o644), ShouldBeNil)
+		So(ex.loadAnnouncements(path), ShouldBeNil)
+
+		Convey("Should list them", func() {
+			announcements := current()
+			So(announcements, ShouldHaveLength, 2)
+			So(announcements[0].Title, ShouldEqual, "ETH/USDT is listed")
+			So(announcements[1].Title, ShouldEqual, "Scheduled maintenance")
+		})
+
+		Convey("Should list the file's announcements once reloaded", func() {
+			listing := current()[0]
+			So(os.WriteFile(path, []byte(`[{"kind": "LISTING", "title": "ETH/USDT is listed", "markets": ["ETHUSDT"]}]`), 0o644), ShouldBeNil)
+			So(ex.loadAnnouncements(path), ShouldBeNil)
+
+			So(current(), ShouldResemble, []AnnouncementData{listing})
+		})
+
+		Convey("Should keep the current ones when the file is invalid", func() {
+			So(os.WriteFile(path, []byte(`[{"kind": "OUTAGE", "title": "Down"}]`), 0o644), ShouldBeNil)
+			So(ex.loadAnnouncements(path), ShouldNotBeNil)
+			So(os.WriteFile(path, []byte(`not json`), 0o644), ShouldBeNil)
+			So(ex.loadAnnouncements(path), ShouldNotBeNil)
+
+			So(current(), ShouldHaveLength, 2)
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/labstack/echo/v4"
+)
+
+// maxBatchSize caps the items of a batch, which hold their market's matching goroutine while they're processed
+const maxBatchSize = 50
+
+const (
+	batchActionPlace  = "place"
+	batchActionCancel = "cancel"
+)
+
+// BatchRequest is a sequence of placements and cancellations in one market. Place items may leave out
+// their market.
+type BatchRequest struct {
+	Market Market             `json:"market"`
+	Items  []BatchItemRequest `json:"items"`
+}
+
+// BatchItemRequest is an order to place, with the fields of PlaceOrderRequest, or an order ID to cancel.
+type BatchItemRequest struct {
+	Action string `json:"action"`
+	ID     int64  `json:"id"`
+	PlaceOrderRequest
+}
+
+type BatchItemResult struct {
+	Status int            `json:"status"`
+	Result map[string]any `json:"result"`
+}
+
+// handleBatch processes the items in sequence and reports each item's outcome. The whole batch is applied
+// by its market's matching engine in one go, so no other request lands on the book between two items.
+// A failed item doesn't stop the rest of the batch.
+func (ex *Exchange) handleBatch(c echo.Context) error {
+	var req BatchRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return err
+	}
+	if len(req.Items) == 0 || len(req.Items) > maxBatchSize {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"msg": localize(c, "a batch needs 1 to %d items", maxBatchSize),
+		})
+	}
+	market, _ := ex.symbols.Resolve(string(req.Market))
+	engine, exist := ex.engines[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	// Placements are checked up front, off the matching goroutine, as a single order would be
+	results := make([]orderResult, len(req.Items))
+	orders := make([]*entity.Order, len(req.Items))
+	for i, item := range req.Items {
+		if item.Action != batchActionPlace {
+			continue
+		}
+		if item.Market == "" {
+			req.Items[i].Market = req.Market
+		}
+		if itemMarket, _ := ex.symbols.Resolve(string(req.Items[i].Market)); itemMarket != market {
+			results[i] = orderResult{http.StatusBadRequest, map[string]any{
+				"msg": localize(c, "batch items must be in the batch's market"),
+			}, nil}
+			continue
+		}
+		if _, order, rejected, ok := ex.newOrder(c, req.Items[i].PlaceOrderRequest); ok {
+			orders[i] = order
+		} else {
+			results[i] = rejected
+		}
+	}
+
+	engine.Do(func(orderBook *entity.OrderBook) {
+		for i, item := range req.Items {
+			switch {
+			case results[i].status != 0:
+				// Turned away before reaching the book
+			case item.Action == batchActionPlace:
+				results[i] = sequenced(ex.placeOnBook(c, market, orderBook, req.Items[i].PlaceOrderRequest, orders[i]), orderBook)
+			case item.Action == batchActionCancel:
+				order, found := orderBook.LookupOrder(item.ID)
+				if !found {
+					results[i] = orderResult{http.StatusNotFound, map[string]any{
+						"msg": localize(c, "order id not found"),
+					}, nil}
+					continue
+				}
+				results[i] = sequenced(ex.cancelOnBook(c, market, orderBook, order), orderBook)
+			default:
+				results[i] = orderResult{http.StatusBadRequest, map[string]any{
+					"msg": localize(c, "invalid action %q", item.Action),
+				}, nil}
+			}
+		}
+	})
+
+	itemResults := make([]BatchItemResult, 0, len(results))
+	for i, res := range results {
+		if res.err != nil {
+			c.Logger().Errorf("handleBatch: item %d: %v", i, res.err)
+		}
+		itemResults = append(itemResults, BatchItemResult{
+			Status: res.status,
+			Result: res.body,
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"results": itemResults,
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Requests are served on their own goroutines while the matching engines, the expiry sweepers and the TWAP
+// scheduler run in the background. Run with -race.
+func TestConcurrentRequests(t *testing.T) {
+	Convey("When placing, cancelling and reading orders concurrently", t, func() {
+		e := echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(e.Group("/api/v1"))
+		for _, engine := range ex.engines {
+			engine.Start()
+		}
+		for _, sweeper := range ex.sweepers {
+			sweeper.Start()
+		}
+		ex.twap.Start()
+		defer func() {
+			for _, sweeper := range ex.sweepers {
+				sweeper.Stop()
+			}
+			ex.twap.Stop()
+			for _, engine := range ex.engines {
+				engine.Stop()
+			}
+		}()
+
+		serve := func(method, path, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		const workers, orders = 8, 50
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				placement := "BID"
+				if i%2 == 1 {
+					placement = "ASK"
+				}
+				for j := 0; j < orders; j++ {
+					rec := serve(http.MethodPost, "/api/v1/order", fmt.Sprintf(
+						`{"type": "LIMIT_ORDER", "placement": %q, "size": 1, "price": %d, "market": "ETH"}`,
+						placement, 100+j%5,
+					))
+					var res struct {
+						Order OrderData `json:"order"`
+					}
+					json.Unmarshal(rec.Body.Bytes(), &res)
+					if j%3 == 0 {
+						serve(http.MethodDelete, fmt.Sprintf("/api/v1/order/cancel/%d", res.Order.ID), "")
+					}
+					serve(http.MethodGet, "/api/v1/book/ETH", "")
+					serve(http.MethodGet, "/api/v1/stats", "")
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		Convey("Should leave a consistent book", func() {
+			rec := serve(http.MethodGet, "/api/v1/stats", "")
+			So(rec.Code, ShouldEqual, 200)
+
+			var stats struct {
+				OrderIndexSize int            `json:"order_index_size"`
+				RestingOrders  map[string]int `json:"resting_orders"`
+			}
+			So(json.Unmarshal(rec.Body.Bytes(), &stats), ShouldBeNil)
+
+			resting := 0
+			for _, count := range stats.RestingOrders {
+				resting += count
+			}
+			So(stats.OrderIndexSize, ShouldEqual, resting)
+
+			var bids, asks []*entity.Limit
+			ex.engines[MarketETH].Do(func(book *entity.OrderBook) {
+				bids, asks = book.Bids(), book.Asks()
+			})
+			if len(bids) > 0 && len(asks) > 0 {
+				So(bids[0].Price, ShouldBeLessThan, asks[0].Price)
+			}
+		})
+	})
+}
+
+func TestBatchAtomicity(t *testing.T) {
+	Convey("When single orders race a batch at the same price", t, func() {
+		e := echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(e.Group("/api/v1"))
+		for _, engine := range ex.engines {
+			engine.Start()
+			defer engine.Stop()
+		}
+
+		serve := func(path, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		const batches, workers = 50, 4
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						serve("/api/v1/order", `{"type": "LIMIT_ORDER", "placement": "BID", "size": 1, "price": 100, "market": "ETH", "user": "other"}`)
+					}
+				}
+			}()
+		}
+
+		batchOrders := [][2]int64{}
+		for i := 0; i < batches; i++ {
+			item := `{"action": "place", "type": "LIMIT_ORDER", "placement": "BID", "size": 1, "price": 100, "user": "batch"}`
+			rec := serve("/api/v1/orders/batch", fmt.Sprintf(`{"market": "ETH", "items": [%s, %s]}`, item, item))
+
+			var res struct {
+				Results []struct {
+					Status int `json:"status"`
+					Result struct {
+						Order OrderData `json:"order"`
+					} `json:"result"`
+				} `json:"results"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			if len(res.Results) == 2 {
+				batchOrders = append(batchOrders, [2]int64{res.Results[0].Result.Order.ID, res.Results[1].Result.Order.ID})
+			}
+		}
+		close(done)
+		wg.Wait()
+
+		Convey("Should queue the items of each batch next to each other", func() {
+			So(batchOrders, ShouldHaveLength, batches)
+
+			position := map[int64]int{}
+			ex.engines[MarketETH].Do(func(book *entity.OrderBook) {
+				for i, order := range book.Bids()[0].Orders.All() {
+					position[order.ID] = i
+				}
+			})
+			for _, ids := range batchOrders {
+				So(position[ids[1]], ShouldEqual, position[ids[0]]+1)
+			}
+		})
+
+		Convey("Should refuse items of another market", func() {
+			rec := serve("/api/v1/orders/batch", `{"market": "ETH", "items": [{"action": "place", "type": "LIMIT_ORDER", "placement": "BID", "size": 1, "price": 100, "market": "BTC"}]}`)
+
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Body.String(), ShouldContainSubstring, `"status":400`)
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultDepthLimit = 50
+	// maxDepthLimit caps the levels per side a depth request can ask for
+	maxDepthLimit = 500
+)
+
+// handleGetDepth returns the book's price levels with their total size, best first, optionally aggregated
+// into buckets of agg. limit caps the levels per side.
+func (ex *Exchange) handleGetDepth(c echo.Context) error {
+	market, _ := ex.symbols.Resolve(c.Param("market"))
+	engine, exist := ex.engines[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	limit, aggregation := defaultDepthLimit, 0.0
+	var err error
+	if param := c.QueryParam("limit"); param != "" {
+		if limit, err = strconv.Atoi(param); err != nil {
+			return invalidDepthParameters(c)
+		}
+	}
+	if param := c.QueryParam("agg"); param != "" {
+		if aggregation, err = strconv.ParseFloat(param, 64); err != nil {
+			return invalidDepthParameters(c)
+		}
+	}
+
+	var depth entity.L2Snapshot
+	engine.Do(func(orderBook *entity.OrderBook) {
+		depth, err = orderBook.Depth(min(limit, maxDepthLimit), aggregation)
+	})
+	if err != nil {
+		c.Logger().Warnf("handleGetDepth: %v", err)
+		return invalidDepthParameters(c)
+	}
+
+	return c.JSON(200, depth)
+}
+
+// handleGetL3 returns every resting order with its queue position at its level, without its owner.
+func (ex *Exchange) handleGetL3(c echo.Context) error {
+	market, _ := ex.symbols.Resolve(c.Param("market"))
+	engine, exist := ex.engines[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	var snapshot entity.L3Snapshot
+	engine.Do(func(orderBook *entity.OrderBook) {
+		snapshot = orderBook.L3Snapshot()
+	})
+	for _, levels := range [][]entity.L3Level{snapshot.Bids, snapshot.Asks} {
+		for _, level := range levels {
+			for i := range level.Orders {
+				_, level.Orders[i].TimestampISO = apiTime(c, time.UnixMilli(level.Orders[i].Timestamp))
+			}
+		}
+	}
+	return c.JSON(200, snapshot)
+}
+
+func invalidDepthParameters(c echo.Context) error {
+	return c.JSON(http.StatusBadRequest, map[string]any{
+		"msg": localize(c, "invalid depth parameters"),
+	})
+}
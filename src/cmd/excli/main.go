@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/spf13/cobra"
+)
+
+/*
+	excli is a small trading client for a running exchange.
+
+	Environments are selected with --profile, read from ~/.excli.json:
+	{
+		"local":   {"url": "http://localhost:3000"},
+		"staging": {"url": "https://staging.example.com"}
+	}
+*/
+
+const defaultURL = "http://localhost:3000"
+
+type Profile struct {
+	URL string `json:"url"`
+}
+
+type client struct {
+	baseURL string
+	http    *http.Client
+}
+
+var (
+	profileName string
+	baseURL     string
+)
+
+func main() {
+	root := &cobra.Command{
+		Use:           "excli",
+		Short:         "Command line client for the crypto exchange",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&profileName, "profile", "", "profile name in ~/.excli.json")
+	root.PersistentFlags().StringVar(&baseURL, "url", "", "exchange base URL, overrides the profile")
+
+	root.AddCommand(newOrderCmd(), newBookCmd())
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newOrderCmd() *cobra.Command {
+	orderCmd := &cobra.Command{
+		Use:   "order",
+		Short: "Place or cancel orders",
+	}
+
+	var (
+		orderType   string
+		placement   string
+		size        float64
+		price       float64
+		stopPrice   float64
+		timeInForce string
+		postOnly    bool
+		expiresIn   time.Duration
+		maxSlippage float64
+		minFillSize float64
+		market      string
+	)
+	placeCmd := &cobra.Command{
+		Use:   "place",
+		Short: "Place a limit or market order",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			var expiresAt int64
+			if expiresIn > 0 {
+				expiresAt = time.Now().Add(expiresIn).UnixMilli()
+			}
+
+			return c.do(http.MethodPost, "/api/v1/order", map[string]any{
+				"type":             strings.ToUpper(orderType) + "_ORDER",
+				"placement":        strings.ToUpper(placement),
+				"size":             size,
+				"price":            price,
+				"stop_price":       stopPrice,
+				"time_in_force":    strings.ToUpper(timeInForce),
+				"post_only":        postOnly,
+				"expires_at":       expiresAt,
+				"max_slippage_pct": maxSlippage,
+				"min_fill_size":    minFillSize,
+				"market":           strings.ToUpper(market),
+			})
+		},
+	}
+	placeCmd.Flags().StringVar(&orderType, "type", "limit", "order type: limit, market, stop or stop_limit")
+	placeCmd.Flags().StringVar(&placement, "side", "", "order side: bid or ask")
+	placeCmd.Flags().Float64Var(&size, "size", 0, "order size")
+	placeCmd.Flags().Float64Var(&price, "price", 0, "limit price")
+	placeCmd.Flags().Float64Var(&stopPrice, "stop-price", 0, "trigger price of a stop order")
+	placeCmd.Flags().StringVar(&timeInForce, "time-in-force", "gtc", "time in force: gtc or ioc")
+	placeCmd.Flags().BoolVar(&postOnly, "post-only", false, "reject the limit order instead of matching if it would cross")
+	placeCmd.Flags().DurationVar(&expiresIn, "expires-in", 0, "cancel the order if still open after this long, e.g. 1h")
+	placeCmd.Flags().Float64Var(&maxSlippage, "max-slippage", 0, "stop a market order from filling more than this percentage past the best price")
+	placeCmd.Flags().Float64Var(&minFillSize, "min-fill-size", 0, "skip fills smaller than this and reject the order if it can't fill that much")
+	placeCmd.Flags().StringVar(&market, "market", "ETH", "market symbol")
+	placeCmd.MarkFlagRequired("side")
+	placeCmd.MarkFlagRequired("size")
+
+	cancelCmd := &cobra.Command{
+		Use:   "cancel <order-id>",
+		Short: "Cancel an order by ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			return c.do(http.MethodDelete, "/api/v1/order/cancel/"+args[0], nil)
+		},
+	}
+
+	var replacePrice, replaceSize float64
+	replaceCmd := &cobra.Command{
+		Use:   "replace <order-id>",
+		Short: "Change the price and/or size of a resting order",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			return c.do(http.MethodPut, "/api/v1/order/"+args[0], map[string]any{
+				"price": replacePrice,
+				"size":  replaceSize,
+			})
+		},
+	}
+	replaceCmd.Flags().Float64Var(&replacePrice, "price", 0, "new limit price, unchanged if omitted")
+	replaceCmd.Flags().Float64Var(&replaceSize, "size", 0, "new remaining size, unchanged if omitted")
+
+	var cancelAllMarket, cancelAllUser string
+	cancelAllCmd := &cobra.Command{
+		Use:   "cancel-all",
+		Short: "Cancel every open order of a market and/or user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{}
+			if cancelAllMarket != "" {
+				query.Set("market", cancelAllMarket)
+			}
+			if cancelAllUser != "" {
+				query.Set("user", cancelAllUser)
+			}
+			return c.do(http.MethodDelete, "/api/v1/orders?"+query.Encode(), nil)
+		},
+	}
+	cancelAllCmd.Flags().StringVar(&cancelAllMarket, "market", "", "only cancel orders of this market")
+	cancelAllCmd.Flags().StringVar(&cancelAllUser, "user", "", "only cancel orders of this user")
+
+	orderCmd.AddCommand(placeCmd, replaceCmd, cancelCmd, cancelAllCmd)
+	return orderCmd
+}
+
+func newBookCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "book <market>",
+		Short: "Show the order book of a market",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			return c.do(http.MethodGet, "/api/v1/book/"+args[0], nil)
+		},
+	}
+}
+
+func newClient() (*client, error) {
+	url := baseURL
+	if url == "" {
+		profile, err := loadProfile(profileName)
+		if err != nil {
+			return nil, err
+		}
+		url = profile.URL
+	}
+
+	return &client{
+		baseURL: strings.TrimSuffix(url, "/"),
+		http:    http.DefaultClient,
+	}, nil
+}
+
+func loadProfile(name string) (Profile, error) {
+	if name == "" {
+		return Profile{URL: defaultURL}, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Profile{}, stacktrace.Propagate(err, "loadProfile: failed to resolve home directory")
+	}
+
+	raw, err := os.ReadFile(filepath.Join(home, ".excli.json"))
+	if err != nil {
+		return Profile{}, stacktrace.Propagate(err, "loadProfile: failed to read profiles")
+	}
+
+	profiles := map[string]Profile{}
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return Profile{}, stacktrace.Propagate(err, "loadProfile: failed to parse profiles")
+	}
+
+	profile, exists := profiles[name]
+	if !exists {
+		return Profile{}, stacktrace.NewError("loadProfile: profile %q not found", name)
+	}
+
+	return profile, nil
+}
+
+// do sends the request and pretty prints the JSON response to stdout.
+func (c *client) do(method, path string, body any) error {
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return stacktrace.Propagate(err, "do: failed to encode request")
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return stacktrace.Propagate(err, "do: failed to build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return stacktrace.Propagate(err, "do: %s %s failed", method, path)
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return stacktrace.Propagate(err, "do: failed to read response")
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		out.Write(raw)
+	}
+	fmt.Println(out.String())
+
+	if res.StatusCode >= 400 {
+		return stacktrace.NewError("do: %s %s returned %d", method, path, res.StatusCode)
+	}
+	return nil
+}
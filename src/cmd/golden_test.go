@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the API response tests")
+
+// volatileFields vary from run to run: order IDs come from a process-wide sequence and timestamps from the
+// clock. Golden files hold them as zero, as long as they are still numbers.
+var volatileFields = map[string]bool{
+	"id":          true,
+	"timestamp":   true,
+	"server_time": true,
+}
+
+// assertGolden compares the JSON response body with testdata/<name>.golden, or rewrites the file with -update.
+// Field names, nesting and value types must match exactly.
+func assertGolden(name string, body []byte) {
+	var decoded any
+	So(json.Unmarshal(body, &decoded), ShouldBeNil)
+	scrubVolatile(decoded)
+	got, err := json.MarshalIndent(decoded, "", "  ")
+	So(err, ShouldBeNil)
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		So(os.WriteFile(path, got, 0o644), ShouldBeNil)
+	}
+	want, err := os.ReadFile(path)
+	So(err, ShouldBeNil)
+	So(string(got), ShouldEqual, string(want))
+}
+
+func scrubVolatile(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if _, isNumber := field.(float64); isNumber && volatileFields[key] {
+				v[key] = 0
+				continue
+			}
+			scrubVolatile(field)
+		}
+	case []any:
+		for _, item := range v {
+			scrubVolatile(item)
+		}
+	}
+}
+
+func TestGoldenResponses(t *testing.T) {
+	Convey("When calling the API", t, func() {
+		e := echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(e.Group("/api/v1"))
+		for _, engine := range ex.engines {
+			engine.Start()
+		}
+		defer func() {
+			for _, engine := range ex.engines {
+				engine.Stop()
+			}
+		}()
+
+		serve := func(method, path, body string) []byte {
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return bytes.TrimSpace(rec.Body.Bytes())
+		}
+
+		resting := serve(http.MethodPost, "/api/v1/order", `{"type": "LIMIT_ORDER", "placement": "ASK", "size": 5, "price": 100, "market": "ETH", "user": "alice"}`)
+		serve(http.MethodPost, "/api/v1/order", `{"type": "LIMIT_ORDER", "placement": "BID", "size": 2, "price": 99, "market": "ETH", "user": "carol"}`)
+
+		Convey("Should keep the shape of a resting limit order", func() {
+			assertGolden("place_limit_order", resting)
+		})
+
+		Convey("Should keep the shape of a filled market order", func() {
+			assertGolden("place_market_order", serve(http.MethodPost, "/api/v1/order", `{"type": "MARKET_ORDER", "placement": "BID", "size": 2, "market": "ETH", "user": "bob"}`))
+		})
+
+		Convey("Should keep the shape of a rejected order", func() {
+			assertGolden("reject_order", serve(http.MethodPost, "/api/v1/order", `{"type": "MARKET_ORDER", "placement": "BID", "size": 50, "market": "ETH"}`))
+		})
+
+		Convey("Should keep the shape of the book", func() {
+			assertGolden("book", serve(http.MethodGet, "/api/v1/book/ETH", ""))
+		})
+
+		Convey("Should keep the shape of the depth", func() {
+			assertGolden("depth", serve(http.MethodGet, "/api/v1/depth/ETH?limit=10&agg=0.5", ""))
+		})
+
+		Convey("Should keep the shape of the L3 view", func() {
+			assertGolden("l3", serve(http.MethodGet, "/api/v1/l3/ETH", ""))
+		})
+
+		Convey("Should keep the shape of a cancellation", func() {
+			var placed struct {
+				Order OrderData `json:"order"`
+			}
+			So(json.Unmarshal(resting, &placed), ShouldBeNil)
+			assertGolden("cancel_order", serve(http.MethodDelete, fmt.Sprintf("/api/v1/order/cancel/%d", placed.Order.ID), ""))
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultLanguage = "en"
+
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+// messageCatalog holds the translations of response messages by language, keyed by the English message.
+// Only human-readable messages are translated; codes such as reject_reason and cancel_reason stay as they are.
+// Messages missing from a language fall back to English.
+var messageCatalog = map[string]map[string]string{
+	"id": {
+		"a batch needs 1 to %d items":                    "batch harus berisi 1 sampai %d item",
+		"an OCO order needs exactly two legs":            "order OCO harus terdiri dari tepat dua order",
+		"batch items must be in the batch's market":      "item batch harus berada di market batch",
+		"error occured when executing order cancelation": "terjadi kesalahan saat membatalkan order",
+		"expires_at is in the past":                      "expires_at sudah lewat",
+		"failed to place order":                          "gagal menempatkan order",
+		"invalid action %q":                              "aksi %q tidak valid",
+		"invalid depth parameters":                       "parameter depth tidak valid",
+		"invalid order type":                             "tipe order tidak valid",
+		"invalid order_id":                               "order_id tidak valid",
+		"invalid timestamp":                              "timestamp tidak valid",
+		"market not found":                               "market tidak ditemukan",
+		"market or user is required":                     "market atau user wajib diisi",
+		"order ID not found":                             "ID order tidak ditemukan",
+		"order deleted":                                  "order dihapus",
+		"order id not found":                             "id order tidak ditemukan",
+		"order placed":                                   "order ditempatkan",
+		"order rejected":                                 "order ditolak",
+		"order replaced":                                 "order diubah",
+		"orders deleted":                                 "order-order dihapus",
+		"quotes partially replaced":                      "kuotasi diganti sebagian",
+		"quotes replaced":                                "kuotasi diganti",
+		"timestamp outside of allowed clock skew":        "timestamp di luar batas selisih waktu yang diizinkan",
+	},
+}
+
+// localize translates the message to the request's preferred language and formats it with vals.
+// It sets Content-Language to the language used.
+func localize(c echo.Context, msg string, vals ...any) string {
+	lang := negotiateLanguage(c.Request().Header.Get(headerAcceptLanguage))
+	if translated, ok := messageCatalog[lang][msg]; ok {
+		msg = translated
+	} else {
+		lang = defaultLanguage
+	}
+	c.Response().Header().Set(headerContentLanguage, lang)
+
+	if len(vals) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, vals...)
+}
+
+// negotiateLanguage picks the most preferred language of an Accept-Language header that has a catalog,
+// matching on the primary subtag so id-ID picks id. It falls back to English.
+func negotiateLanguage(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	candidates := []candidate{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 && lang != "" {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, candidate := range candidates {
+		if candidate.lang == defaultLanguage {
+			return defaultLanguage
+		}
+		if _, ok := messageCatalog[candidate.lang]; ok {
+			return candidate.lang
+		}
+	}
+	return defaultLanguage
+}
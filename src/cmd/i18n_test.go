@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocalize(t *testing.T) {
+	Convey("When negotiating the response language", t, func() {
+		Convey("Should pick the most preferred language with a catalog", func() {
+			So(negotiateLanguage("id-ID,id;q=0.9,en;q=0.8"), ShouldEqual, "id")
+			So(negotiateLanguage("fr;q=0.9,id;q=0.5"), ShouldEqual, "id")
+			So(negotiateLanguage("en;q=0.9,id;q=0.5"), ShouldEqual, "en")
+		})
+
+		Convey("Should fall back to English", func() {
+			So(negotiateLanguage(""), ShouldEqual, "en")
+			So(negotiateLanguage("fr, de;q=0.5"), ShouldEqual, "en")
+			So(negotiateLanguage("id;q=0"), ShouldEqual, "en")
+		})
+	})
+
+	Convey("When localizing a message", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(headerAcceptLanguage, "id-ID")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		Convey("Should translate and format it", func() {
+			So(localize(c, "market not found"), ShouldEqual, "market tidak ditemukan")
+			So(localize(c, "a batch needs 1 to %d items", 50), ShouldEqual, "batch harus berisi 1 sampai 50 item")
+			So(rec.Header().Get(headerContentLanguage), ShouldEqual, "id")
+		})
+
+		Convey("Should keep untranslated messages in English", func() {
+			So(localize(c, "something new"), ShouldEqual, "something new")
+			So(rec.Header().Get(headerContentLanguage), ShouldEqual, "en")
+		})
+
+		Convey("Translations should keep the format verbs of the message", func() {
+			for lang, messages := range messageCatalog {
+				for msg, translated := range messages {
+					So(strings.Count(translated, "%"), ShouldEqual, strings.Count(msg, "%"))
+					So(lang, ShouldNotEqual, defaultLanguage)
+				}
+			}
+		})
+	})
+}
@@ -2,13 +2,19 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
 	"github.com/palantir/stacktrace"
 )
 
@@ -18,24 +24,132 @@ func main() {
 		c.Logger().Error(err)
 	}
 
-	ex := NewExchange()
-	e.POST("/order", ex.handlePlaceOrder)
-
-	e.GET("/book/:market", ex.handleGetBook)
+	e.Logger.SetLevel(log.INFO)
 
-	e.DELETE("/order/cancel/:id", ex.handleCancelOrder)
+	ex := NewExchange()
+	if spec := os.Getenv("AUCTION_WINDOWS"); spec != "" {
+		if err := ex.scheduleAuctions(spec); err != nil {
+			e.Logger.Fatal(err)
+		}
+	}
+	if path := os.Getenv("ANNOUNCEMENTS_FILE"); path != "" {
+		if err := ex.loadAnnouncements(path); err != nil {
+			e.Logger.Fatal(err)
+		}
+		// SIGHUP reloads the file once admins edit it
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		go func() {
+			for range hangup {
+				if err := ex.loadAnnouncements(path); err != nil {
+					e.Logger.Error(err)
+				}
+			}
+		}()
+	}
+	if dir := os.Getenv("COMMAND_LOG_DIR"); dir != "" {
+		if err := ex.logCommands(dir); err != nil {
+			e.Logger.Fatal(err)
+		}
+	}
+	for _, engine := range ex.engines {
+		engine.Start()
+	}
+	for _, sweeper := range ex.sweepers {
+		sweeper.Start()
+	}
+	for _, auction := range ex.auctions {
+		auction.Start()
+	}
+	ex.twap.Start()
+	ex.registerRoutes(e.Group("/api/v1", accessLog(accessLogMaxBody)))
+
+	// Unversioned routes are kept as a compatibility shim until the sunset date
+	ex.registerRoutes(e.Group("", deprecated(legacySunset, "/api/v1"), accessLog(accessLogMaxBody)))
+
+	// Operator endpoints are only served on loopback: the exchange has no authentication to restrict them
+	admin := echo.New()
+	admin.HideBanner = true
+	ex.registerAdminRoutes(admin.Group("", accessLog(accessLogMaxBody)))
+	go func() {
+		if err := admin.Start(adminAddress); err != http.ErrServerClosed {
+			e.Logger.Fatal(err)
+		}
+	}()
 
 	e.Start(":3000")
 }
 
-type Market string
+// registerAdminRoutes registers the operator endpoints, which are kept off the public listener.
+func (ex *Exchange) registerAdminRoutes(g *echo.Group) {
+	g.GET("/latency", ex.handleGetLatency)
+}
+
+func (ex *Exchange) registerRoutes(g *echo.Group) {
+	g.GET("/time", ex.handleGetTime)
+
+	g.GET("/stats", ex.handleGetStats)
+
+	g.POST("/order", ex.handlePlaceOrder, recordLatency(ex.latency, latencyPlace), clockSkewGuard(maxClockSkew))
+
+	g.POST("/orders/batch", ex.handleBatch, clockSkewGuard(maxClockSkew))
+
+	g.POST("/quotes", ex.handleMassQuote, clockSkewGuard(maxClockSkew))
+
+	g.POST("/order/oco", ex.handlePlaceOCOOrder, clockSkewGuard(maxClockSkew))
+
+	g.POST("/algo/twap", ex.handlePlaceTWAPOrder, clockSkewGuard(maxClockSkew))
+
+	g.GET("/algo/twap/:id", ex.handleGetTWAPOrder)
+
+	g.GET("/markets/:symbol", ex.handleGetMarket)
+
+	g.GET("/announcements", ex.handleGetAnnouncements)
+
+	g.GET("/book/:market", ex.handleGetBook)
+
+	g.GET("/depth/:market", ex.handleGetDepth)
+
+	g.GET("/l3/:market", ex.handleGetL3)
+
+	g.GET("/quality/:market", ex.handleGetQuality)
+
+	g.PUT("/order/:id", ex.handleReplaceOrder, clockSkewGuard(maxClockSkew))
+
+	g.DELETE("/order/cancel/:id", ex.handleCancelOrder, recordLatency(ex.latency, latencyCancel), clockSkewGuard(maxClockSkew))
+
+	g.DELETE("/orders", ex.handleCancelAll, clockSkewGuard(maxClockSkew))
+}
 
 const (
-	MarketETH Market = "ETH"
+	accessLogMaxBody = 1024
+	adminAddress     = "127.0.0.1:3001"
+	maxClockSkew     = 5 * time.Second
+	qualityWindow    = 24 * time.Hour
+	expirySweepEvery = time.Second
+	twapRunEvery     = 100 * time.Millisecond
+	auctionRunEvery  = time.Second
+
+	latencyPlace  = "place"
+	latencyCancel = "cancel"
 )
 
+// latencyWindows are the rolling windows the latency report covers, the last being the longest.
+var latencyWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
 type Exchange struct {
-	orderBooks map[Market]*entity.OrderBook
+	// engines own the order books: handlers, expiry sweepers and the auction and TWAP schedulers only reach
+	// a book through its market's matching engine
+	engines  map[Market]*usecase.MatchingEngine
+	quality  map[Market]*usecase.MarketQuality
+	sweepers map[Market]*usecase.ExpirySweeper
+	// auctions hold the call auctions of the markets with auction windows
+	auctions map[Market]*usecase.AuctionScheduler
+	twap     *usecase.TWAPScheduler
+	symbols  *SymbolRegistry
+
+	announcements *usecase.AnnouncementBoard
+	latency       *usecase.LatencyTracker
 }
 
 type PlaceOrderRequest struct {
@@ -44,14 +158,79 @@ type PlaceOrderRequest struct {
 	Size      float64               `json:"size"`
 	Price     float64               `json:"price"`
 	Market    Market                `json:"market"`
+	User      string                `json:"user"`
+
+	// AllowPartialFill fills a market order against the available liquidity and cancels the rest
+	AllowPartialFill bool `json:"allow_partial_fill"`
+	// StopPrice is the last trade price that triggers a stop order
+	StopPrice float64 `json:"stop_price"`
+	// TimeInForce is GTC when empty. IOC orders fill what they can immediately and never rest.
+	TimeInForce entity.TimeInForce `json:"time_in_force"`
+	// PostOnly rejects a limit order that would take liquidity instead of matching it
+	PostOnly bool `json:"post_only"`
+	// ExpiresAt makes the order good-till-date, in Unix milliseconds
+	ExpiresAt int64 `json:"expires_at"`
+	// ProtectionPrice and MaxSlippagePct stop a market order from filling past a price, cancelling the remainder
+	ProtectionPrice float64 `json:"protection_price"`
+	MaxSlippagePct  float64 `json:"max_slippage_pct"`
+	// SelfTradePrevention cancels instead of matching the user's own resting orders
+	SelfTradePrevention entity.STPMode `json:"self_trade_prevention"`
+	// MinFillSize skips executions smaller than it and rejects the order if it can't fill that much
+	MinFillSize float64 `json:"min_fill_size"`
+	// PegOffset pegs a limit order to the best price of its side plus the offset instead of resting at Price
+	PegOffset *float64 `json:"peg_offset"`
+	// Bracket springs a take-profit and stop-loss once the limit or market order completes
+	Bracket *entity.Bracket `json:"bracket"`
 }
 
 type OrderData struct {
 	ID             int64                 `json:"id"`
 	OrderPlacement entity.OrderPlacement `json:"order_placement"`
+	User           string                `json:"user,omitempty"`
 	Size           float64               `json:"size"`
+	FilledSize     float64               `json:"filled_size"`
 	Price          float64               `json:"price"`
+	Status         entity.OrderStatus    `json:"status"`
+	CancelReason   entity.CancelReason   `json:"cancel_reason,omitempty"`
+	StopPrice      float64               `json:"stop_price,omitempty"`
+	TimeInForce    entity.TimeInForce    `json:"time_in_force,omitempty"`
+	PostOnly       bool                  `json:"post_only,omitempty"`
+	ExpiresAt      int64                 `json:"expires_at,omitempty"`
+	LinkedOrderID  int64                 `json:"linked_order_id,omitempty"`
+	Pegged         bool                  `json:"pegged,omitempty"`
+	Bracket        *entity.Bracket       `json:"bracket,omitempty"`
+	ParentOrderID  int64                 `json:"parent_order_id,omitempty"`
 	Timestamp      int64                 `json:"timestamp"`
+	TimestampISO   string                `json:"timestamp_iso,omitempty"`
+}
+
+func newOrderData(c echo.Context, order *entity.Order, price float64) *OrderData {
+	timestamp, timestampISO := apiTime(c, time.Unix(0, order.Timestamp))
+	data := &OrderData{
+		ID:             order.ID,
+		OrderPlacement: order.OrderPlacement,
+		User:           order.Owner,
+		Size:           order.Size,
+		FilledSize:     order.FilledSize,
+		Price:          price,
+		Status:         order.Status,
+		CancelReason:   order.CancelReason,
+		StopPrice:      order.StopPrice,
+		TimeInForce:    order.TimeInForce,
+		PostOnly:       order.PostOnly,
+		ExpiresAt:      time.Unix(0, order.ExpiresAt).UnixMilli(),
+		LinkedOrderID:  order.LinkedOrderID,
+		Pegged:         order.Pegged,
+		ParentOrderID:  order.ParentOrderID,
+		Timestamp:      timestamp,
+		TimestampISO:   timestampISO,
+	}
+	// The book writes the IDs of the exits it springs to the bracket, so the response gets its own copy
+	if order.Bracket != nil {
+		bracket := *order.Bracket
+		data.Bracket = &bracket
+	}
+	return data
 }
 
 type OrderBookData struct {
@@ -63,52 +242,190 @@ type OrderBookData struct {
 }
 
 func NewExchange() *Exchange {
-	orderBooks := make(map[Market]*entity.OrderBook)
-	orderBooks[MarketETH] = entity.NewOrderBook(string(MarketETH))
-	return &Exchange{
-		orderBooks: orderBooks,
+	quality := make(map[Market]*usecase.MarketQuality)
+	ex := &Exchange{
+		engines:  make(map[Market]*usecase.MatchingEngine),
+		quality:  quality,
+		sweepers: make(map[Market]*usecase.ExpirySweeper),
+		auctions: make(map[Market]*usecase.AuctionScheduler),
+		symbols:  NewSymbolRegistry(markets),
+
+		announcements: usecase.NewAnnouncementBoard(),
+		latency:       usecase.NewLatencyTracker(latencyWindows[len(latencyWindows)-1]),
+	}
+	for market, info := range markets {
+		orderBook := entity.NewOrderBookWithConfig(string(market), info.Config)
+		quality[market] = usecase.NewMarketQuality(string(market), qualityWindow)
+		orderBook.OnMatch = quality[market].RecordMatch
+		quality[market].RecordBook(orderBook)
+		engine := usecase.NewMatchingEngine(orderBook)
+		ex.engines[market] = engine
+
+		sweeper := usecase.NewExpirySweeper(orderBook, expirySweepEvery, engine)
+		sweeper.OnExpire = func(entity.Orders) {
+			quality[market].RecordBook(orderBook)
+		}
+		ex.sweepers[market] = sweeper
+	}
+
+	ex.twap = usecase.NewTWAPScheduler(twapRunEvery)
+	ex.twap.OnSlice = func(orderBook *entity.OrderBook, _ *usecase.TWAPOrder, _ *entity.Order) {
+		quality[Market(orderBook.Market)].RecordBook(orderBook)
+	}
+	return ex
+}
+
+// logCommands appends the commands applied to each market's book to <dir>/<market>.log, for the replay tool
+// to rebuild the book from. Every start opens a new book in the log.
+func (ex *Exchange) logCommands(dir string) error {
+	for market, engine := range ex.engines {
+		path := filepath.Join(dir, string(market)+".log")
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return stacktrace.Propagate(err, "logCommands: failed to open %s", path)
+		}
+		log := usecase.NewCommandLog(file, string(market), markets[market].Config)
+		if err := log.Err(); err != nil {
+			return stacktrace.Propagate(err, "logCommands: failed to write to %s", path)
+		}
+		engine.Log = log
+		ex.sweepers[market].Log = log
+		if auction, exist := ex.auctions[market]; exist {
+			auction.Log = log
+		}
+	}
+	return nil
+}
+
+// scheduleAuctions holds daily call auctions in the windows of the spec, a comma separated list of
+// MARKET@HH:MM-HH:MM windows in UTC such as "ETH@07:55-08:00,ETH@23:55-00:05".
+func (ex *Exchange) scheduleAuctions(spec string) error {
+	windows := map[Market][]usecase.AuctionWindow{}
+	for _, entry := range strings.Split(spec, ",") {
+		symbol, span, found := strings.Cut(strings.TrimSpace(entry), "@")
+		market, exist := ex.symbols.Resolve(symbol)
+		if !found || !exist {
+			return stacktrace.NewError("scheduleAuctions: invalid auction window %q", entry)
+		}
+		start, end, found := strings.Cut(span, "-")
+		if !found {
+			return stacktrace.NewError("scheduleAuctions: invalid auction window %q", entry)
+		}
+		window := usecase.AuctionWindow{}
+		var err error
+		if window.Start, err = parseTimeOfDay(start); err != nil {
+			return stacktrace.Propagate(err, "scheduleAuctions: invalid auction window %q", entry)
+		}
+		if window.End, err = parseTimeOfDay(end); err != nil {
+			return stacktrace.Propagate(err, "scheduleAuctions: invalid auction window %q", entry)
+		}
+		windows[market] = append(windows[market], window)
+	}
+
+	for market, marketWindows := range windows {
+		engine := ex.engines[market]
+		quality := ex.quality[market]
+		auction := usecase.NewAuctionScheduler(engine.Book, marketWindows, auctionRunEvery, engine)
+		auction.OnUncross = func([]entity.Match) {
+			quality.RecordBook(engine.Book)
+		}
+		ex.auctions[market] = auction
+	}
+	return nil
+}
+
+// parseTimeOfDay parses HH:MM as the duration since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (ex *Exchange) handleGetTime(c echo.Context) error {
+	serverTime, serverTimeISO := apiTime(c, time.Now())
+	res := map[string]any{
+		"server_time": serverTime,
+	}
+	if serverTimeISO != "" {
+		res["server_time_iso"] = serverTimeISO
+	}
+	return c.JSON(200, res)
+}
+
+// handleGetStats exposes live order counts. order_index_size drifting above the sum of resting_orders
+// means orders are leaking in the index.
+func (ex *Exchange) handleGetStats(c echo.Context) error {
+	restingOrders := map[Market]int{}
+	orderIndexSize := 0
+	for market, engine := range ex.engines {
+		engine.Do(func(orderBook *entity.OrderBook) {
+			restingOrders[market] = orderBook.OrderCount()
+			orderIndexSize += orderBook.OrderIndexSize()
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"order_index_size": orderIndexSize,
+		"resting_orders":   restingOrders,
+	})
+}
+
+// handleGetLatency reports the p50, p95 and p99 latencies of order placement and cancellation over each of
+// the rolling latencyWindows, for operators to check the latency SLAs to market makers.
+func (ex *Exchange) handleGetLatency(c echo.Context) error {
+	reports := []usecase.LatencyReport{}
+	for _, window := range latencyWindows {
+		reports = append(reports, ex.latency.Report(window)...)
 	}
+
+	return c.JSON(200, map[string]any{
+		"latency": reports,
+	})
+}
+
+func (ex *Exchange) handleGetQuality(c echo.Context) error {
+	market, _ := ex.symbols.Resolve(c.Param("market"))
+	quality, exist := ex.quality[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	return c.JSON(200, quality.Report())
 }
 
 func (ex *Exchange) handleGetBook(c echo.Context) error {
-	market := Market(strings.ToUpper(c.Param("market")))
-	orderBook, exist := ex.orderBooks[market]
+	market, _ := ex.symbols.Resolve(c.Param("market"))
+	engine, exist := ex.engines[market]
 	if !exist {
 		return c.JSON(http.StatusNotFound, map[string]any{
-			"msg": "market not found",
+			"msg": localize(c, "market not found"),
 		})
 	}
 
 	orderBookData := OrderBookData{
-		Asks:           []*OrderData{},
-		Bids:           []*OrderData{},
-		BidTotalVolume: orderBook.BidTotalVolume(),
-		AskTotalVolume: orderBook.AskTotalVolume(),
-	}
-
-	for _, limit := range orderBook.Asks() {
-		for _, order := range limit.Orders {
-			orderBookData.Asks = append(orderBookData.Asks, &OrderData{
-				ID:             order.ID,
-				OrderPlacement: order.OrderPlacement,
-				Size:           order.Size,
-				Price:          limit.Price,
-				Timestamp:      order.Timestamp,
-			})
-		}
+		Asks: []*OrderData{},
+		Bids: []*OrderData{},
 	}
+	engine.Do(func(orderBook *entity.OrderBook) {
+		orderBookData.BidTotalVolume = orderBook.BidTotalVolume()
+		orderBookData.AskTotalVolume = orderBook.AskTotalVolume()
+
+		for _, limit := range orderBook.Asks() {
+			for _, order := range limit.Orders.All() {
+				orderBookData.Asks = append(orderBookData.Asks, newOrderData(c, order, limit.Price))
+			}
+		}
 
-	for _, limit := range orderBook.Bids() {
-		for _, order := range limit.Orders {
-			orderBookData.Bids = append(orderBookData.Bids, &OrderData{
-				ID:             order.ID,
-				OrderPlacement: order.OrderPlacement,
-				Size:           order.Size,
-				Price:          limit.Price,
-				Timestamp:      order.Timestamp,
-			})
+		for _, limit := range orderBook.Bids() {
+			for _, order := range limit.Orders.All() {
+				orderBookData.Bids = append(orderBookData.Bids, newOrderData(c, order, limit.Price))
+			}
 		}
-	}
+	})
 
 	return c.JSON(200, orderBookData)
 }
@@ -119,90 +436,360 @@ func (ex *Exchange) handlePlaceOrder(c echo.Context) error {
 		return err
 	}
 
-	orderBook := ex.orderBooks[placeOrderRequest.Market]
-	if orderBook == nil {
-		return errors.New("order book is empty")
+	return ex.placeOrder(c, placeOrderRequest).respond(c)
+}
+
+// orderResult is the outcome of an order operation: the response to send and an internal error to propagate.
+type orderResult struct {
+	status int
+	body   map[string]any
+	err    error
+}
+
+func (r orderResult) respond(c echo.Context) error {
+	if err := c.JSON(r.status, r.body); err != nil {
+		return err
+	}
+	return r.err
+}
+
+func (ex *Exchange) placeOrder(c echo.Context, placeOrderRequest PlaceOrderRequest) orderResult {
+	market, order, rejected, ok := ex.newOrder(c, placeOrderRequest)
+	if !ok {
+		return rejected
 	}
 
-	order := entity.NewOrder(placeOrderRequest.Placement, placeOrderRequest.Size)
+	var result orderResult
+	ex.engines[market].Do(func(orderBook *entity.OrderBook) {
+		result = sequenced(ex.placeOnBook(c, market, orderBook, placeOrderRequest, order), orderBook)
+	})
+	return result
+}
+
+// newOrder creates the requested order once it passed the checks that don't need the book. Otherwise ok is
+// false and rejected is the response to send.
+func (ex *Exchange) newOrder(c echo.Context, placeOrderRequest PlaceOrderRequest) (market Market, order *entity.Order, rejected orderResult, ok bool) {
+	market, _ = ex.symbols.Resolve(string(placeOrderRequest.Market))
+	engine := ex.engines[market]
+	if engine == nil {
+		return market, nil, orderResult{http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		}, nil}, false
+	}
 
+	order = entity.NewOrder(placeOrderRequest.Placement, placeOrderRequest.Size)
+	order.Owner = placeOrderRequest.User
+	order.AllowPartialFill = placeOrderRequest.AllowPartialFill
+	order.TimeInForce = placeOrderRequest.TimeInForce
+	order.PostOnly = placeOrderRequest.PostOnly
+	order.ProtectionPrice = placeOrderRequest.ProtectionPrice
+	order.MaxSlippagePct = placeOrderRequest.MaxSlippagePct
+	order.SelfTradePrevention = placeOrderRequest.SelfTradePrevention
+	order.MinFillSize = placeOrderRequest.MinFillSize
+	if placeOrderRequest.ExpiresAt != 0 {
+		if placeOrderRequest.ExpiresAt <= time.Now().UnixMilli() {
+			return market, nil, orderResult{http.StatusBadRequest, map[string]any{
+				"msg":           localize(c, "expires_at is in the past"),
+				"reject_reason": entity.RejectReasonInvalidOrder,
+			}, nil}, false
+		}
+		order.ExpiresAt = time.UnixMilli(placeOrderRequest.ExpiresAt).UnixNano()
+	}
+
+	// Misaligned orders are turned away without waiting for the book. Pegged orders get their price from it.
+	price := 0.0
+	if placeOrderRequest.Type == entity.LimitOrder && placeOrderRequest.PegOffset == nil || placeOrderRequest.Type == entity.StopLimitOrder {
+		price = placeOrderRequest.Price
+	}
+	if err := engine.Book.Config.CheckOrder(price, placeOrderRequest.Size); err != nil {
+		c.Logger().Warnf("placeOrder: %v", err)
+		return market, nil, placeOrderError(c, err, "placeOrder: invalid order"), false
+	}
+	return market, order, orderResult{}, true
+}
+
+// placeOnBook places the order as requested, on the matching goroutine of the market.
+func (ex *Exchange) placeOnBook(c echo.Context, market Market, orderBook *entity.OrderBook, placeOrderRequest PlaceOrderRequest, order *entity.Order) orderResult {
+	engine := ex.engines[market]
 	if placeOrderRequest.Type == entity.LimitOrder {
-		err := orderBook.PlaceLimitOrder(placeOrderRequest.Price, order)
+		command := usecase.PlaceCommand(entity.LimitOrder, placeOrderRequest.Price, order)
+		if placeOrderRequest.Bracket != nil {
+			bracket := newBracket(placeOrderRequest.Bracket)
+			command.Bracket = &bracket
+		} else if placeOrderRequest.PegOffset != nil {
+			command.PegOffset = placeOrderRequest.PegOffset
+		}
+		result, err := engine.Apply(orderBook, command, order)
+
+		price := orderBook.Config.RoundToTick(placeOrderRequest.Price)
+		if placeOrderRequest.PegOffset != nil {
+			price = 0
+			if order.Limit != nil {
+				price = order.Limit.Price
+			}
+		}
 		if err != nil {
-			c.JSON(500, map[string]any{
-				"msg": "failed to place order",
-			})
-			return stacktrace.Propagate(err, "handlePlaceOrder: failed to place limit order")
+			return placeOrderError(c, err, "placeOrder: failed to place limit order")
 		}
+		ex.quality[market].RecordBook(orderBook)
 
-		return c.JSON(200, map[string]any{
-			"msg":   "order placed",
-			"order": *order,
-		})
+		res := map[string]any{
+			"msg":         localize(c, "order placed"),
+			"order":       newOrderData(c, order, price),
+			"matches":     len(result.Matches),
+			"filled_size": order.FilledSize,
+		}
+		// Tell the client why the remainder was cancelled instead of resting
+		if order.CancelReason != "" {
+			res["status"] = order.CancelReason
+		}
+		return orderResult{200, res, nil}
+	} else if placeOrderRequest.Type == entity.StopOrder || placeOrderRequest.Type == entity.StopLimitOrder {
+		if placeOrderRequest.Type == entity.StopLimitOrder {
+			order.LimitPrice = placeOrderRequest.Price
+		}
+		_, err := engine.Apply(orderBook, usecase.PlaceCommand(placeOrderRequest.Type, placeOrderRequest.StopPrice, order), order)
+		if err != nil {
+			return placeOrderError(c, err, "placeOrder: failed to place stop order")
+		}
+		ex.quality[market].RecordBook(orderBook)
+
+		return orderResult{200, map[string]any{
+			"msg":   localize(c, "order placed"),
+			"order": newOrderData(c, order, order.LimitPrice),
+		}, nil}
 	} else if placeOrderRequest.Type == entity.MarketOrder {
-		matches, err := orderBook.PlaceMarketOrder(order)
+		command := usecase.PlaceCommand(entity.MarketOrder, 0, order)
+		if placeOrderRequest.Bracket != nil {
+			bracket := newBracket(placeOrderRequest.Bracket)
+			command.Bracket = &bracket
+		}
+		result, err := engine.Apply(orderBook, command, order)
 		if err != nil {
-			c.JSON(500, map[string]any{
-				"msg": "failed to place order",
-			})
-			return stacktrace.Propagate(err, "handlePlaceOrder: failed to place market order")
+			return placeOrderError(c, err, "placeOrder: failed to place market order")
 		}
+		ex.quality[market].RecordBook(orderBook)
 
-		return c.JSON(200, map[string]any{
-			"msg":     "order placed",
-			"order":   *order,
-			"matches": len(matches),
-		})
+		res := map[string]any{
+			"msg":         localize(c, "order placed"),
+			"order":       newOrderData(c, order, 0),
+			"matches":     len(result.Matches),
+			"filled_size": order.FilledSize,
+		}
+		// Tell the client how much was left unfilled and why
+		if order.CancelReason != "" {
+			res["status"] = order.CancelReason
+			res["unfilled_size"] = order.Size
+		}
+		return orderResult{200, res, nil}
 	}
 
-	return c.JSON(400, map[string]any{
-		"msg": "invalid order type",
-	})
+	return orderResult{400, map[string]any{
+		"msg":           localize(c, "invalid order type"),
+		"reject_reason": entity.RejectReasonInvalidOrder,
+	}, nil}
+}
+
+// sequenced adds the book's mutation sequence to a successful result, so clients can order it against the
+// matches and status changes of the market.
+func sequenced(result orderResult, orderBook *entity.OrderBook) orderResult {
+	if result.status == http.StatusOK {
+		result.body["sequence"] = orderBook.MutationSequence()
+	}
+	return result
+}
+
+// newBracket takes the exit prices of a requested bracket, leaving out the exit order IDs set by the book.
+func newBracket(req *entity.Bracket) entity.Bracket {
+	return entity.Bracket{
+		TakeProfitPrice:    req.TakeProfitPrice,
+		StopLossPrice:      req.StopLossPrice,
+		StopLossLimitPrice: req.StopLossLimitPrice,
+	}
+}
+
+// placeOrderError responds with the reject reason for rejected orders and a generic failure otherwise.
+func placeOrderError(c echo.Context, err error, msg string) orderResult {
+	if reason, rejected := entity.RejectReasonOf(err); rejected {
+		return orderResult{http.StatusBadRequest, map[string]any{
+			"msg":           localize(c, "order rejected"),
+			"reject_reason": reason,
+		}, nil}
+	}
+
+	return orderResult{http.StatusInternalServerError, map[string]any{
+		"msg": localize(c, "failed to place order"),
+	}, stacktrace.Propagate(err, msg)}
 }
 
 func (ex *Exchange) handleCancelOrder(c echo.Context) error {
 	orderId := c.Param("id")
 	if orderId == "" {
 		return c.JSON(http.StatusNotFound, map[string]any{
-			"msg": "order ID not found",
+			"msg": localize(c, "order ID not found"),
 		})
 	}
 
 	orderIdInt64, err := strconv.ParseInt(orderId, 10, 64)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]any{
-			"msg": "invalid order_id",
+			"msg": localize(c, "invalid order_id"),
 		})
 	}
 
-	order, exists := entity.OrderIndex[orderIdInt64]
-	if !exists {
-		return c.JSON(http.StatusNotFound, map[string]any{
-			"msg": "order id not found",
+	return ex.cancelOrder(c, orderIdInt64).respond(c)
+}
+
+// withOrder runs fn on the matching goroutine of the market whose book holds the live order, and reports
+// whether any does. Order IDs are unique across books, so at most one book holds it.
+func (ex *Exchange) withOrder(orderId int64, fn func(Market, *entity.OrderBook, *entity.Order)) bool {
+	for market, engine := range ex.engines {
+		found := false
+		engine.Do(func(orderBook *entity.OrderBook) {
+			var order *entity.Order
+			if order, found = orderBook.LookupOrder(orderId); found {
+				fn(market, orderBook, order)
+			}
 		})
+		if found {
+			return true
+		}
 	}
+	return false
+}
 
-	orderBook, exists := ex.orderBooks[Market(order.Market)]
-	if !exists {
-		return c.JSON(http.StatusNotFound, map[string]any{
-			"msg": "market not found",
-		})
+func (ex *Exchange) cancelOrder(c echo.Context, orderId int64) orderResult {
+	var result orderResult
+	found := ex.withOrder(orderId, func(market Market, orderBook *entity.OrderBook, order *entity.Order) {
+		result = sequenced(ex.cancelOnBook(c, market, orderBook, order), orderBook)
+	})
+	if !found {
+		return orderResult{http.StatusNotFound, map[string]any{
+			"msg": localize(c, "order id not found"),
+		}, nil}
 	}
+	return result
+}
 
-	err = orderBook.CancelOrderByID(orderIdInt64, order.Order.OrderPlacement)
+// cancelOnBook cancels the order on the matching goroutine of its market.
+func (ex *Exchange) cancelOnBook(c echo.Context, market Market, orderBook *entity.OrderBook, order *entity.Order) orderResult {
+	orderId := order.ID
+	_, err := ex.engines[market].Apply(orderBook, usecase.Command{Type: usecase.CommandCancel, OrderID: orderId})
 	if err == entity.ErrNotFound {
-		return c.JSON(http.StatusNotFound, map[string]any{
-			"msg": "order id not found",
-		})
+		return orderResult{http.StatusNotFound, map[string]any{
+			"msg": localize(c, "order id not found"),
+		}, nil}
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"msg": "error occured when executing order cancelation",
+		return orderResult{http.StatusInternalServerError, map[string]any{
+			"msg": localize(c, "error occured when executing order cancelation"),
+		}, stacktrace.Propagate(err, "cancelOrder: failed to cancel order id %d", orderId)}
+	}
+	ex.quality[market].RecordBook(orderBook)
+
+	return orderResult{200, map[string]any{
+		"msg":           localize(c, "order deleted"),
+		"cancel_reason": order.CancelReason,
+	}, nil}
+}
+
+// handleCancelAll cancels every open order of a market, of a user, or of a user in a market in one go.
+func (ex *Exchange) handleCancelAll(c echo.Context) error {
+	user := c.QueryParam("user")
+	marketParam := c.QueryParam("market")
+	if user == "" && marketParam == "" {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"msg": localize(c, "market or user is required"),
 		})
-		return stacktrace.Propagate(err, "handleCancelOrder: failed to cancel order id %d", orderIdInt64)
+	}
+
+	engines := ex.engines
+	if marketParam != "" {
+		market, _ := ex.symbols.Resolve(marketParam)
+		engine, exist := ex.engines[market]
+		if !exist {
+			return c.JSON(http.StatusNotFound, map[string]any{
+				"msg": localize(c, "market not found"),
+			})
+		}
+		engines = map[Market]*usecase.MatchingEngine{market: engine}
+	}
+
+	cancelled := map[Market][]int64{}
+	sequences := map[Market]int64{}
+	for market, engine := range engines {
+		orderIds := []int64{}
+		engine.Do(func(orderBook *entity.OrderBook) {
+			// Cancelling a partially filled bracket entry springs its exits, so it goes through Apply to log them
+			result, _ := engine.Apply(orderBook, usecase.Command{Type: usecase.CommandCancelAll, Owner: user, Reason: entity.CancelReasonUserRequested})
+			for _, order := range result.Orders {
+				orderIds = append(orderIds, order.ID)
+			}
+			ex.quality[market].RecordBook(orderBook)
+			sequences[market] = orderBook.MutationSequence()
+		})
+		cancelled[market] = orderIds
 	}
 
 	return c.JSON(200, map[string]any{
-		"msg": "order deleted",
+		"msg":       localize(c, "orders deleted"),
+		"cancelled": cancelled,
+		"sequences": sequences,
+	})
+}
+
+type ReplaceOrderRequest struct {
+	// Price and Size replace the order's price and remaining size. Zero keeps the current value.
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+func (ex *Exchange) handleReplaceOrder(c echo.Context) error {
+	orderIdInt64, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"msg": localize(c, "invalid order_id"),
+		})
+	}
+
+	var req ReplaceOrderRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return err
+	}
+
+	var result orderResult
+	found := ex.withOrder(orderIdInt64, func(market Market, orderBook *entity.OrderBook, order *entity.Order) {
+		result = sequenced(ex.replaceOnBook(c, market, orderBook, order, req), orderBook)
 	})
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "order id not found"),
+		})
+	}
+	return result.respond(c)
+}
+
+// replaceOnBook replaces the order on the matching goroutine of its market.
+func (ex *Exchange) replaceOnBook(c echo.Context, market Market, orderBook *entity.OrderBook, order *entity.Order, req ReplaceOrderRequest) orderResult {
+	result, err := ex.engines[market].Apply(orderBook, usecase.Command{Type: usecase.CommandAmend, OrderID: order.ID, Price: req.Price, Size: req.Size})
+	if err == entity.ErrNotFound {
+		return orderResult{http.StatusNotFound, map[string]any{
+			"msg": localize(c, "order id not found"),
+		}, nil}
+	}
+	if err != nil {
+		return placeOrderError(c, err, "handleReplaceOrder: failed to replace order")
+	}
+	ex.quality[market].RecordBook(orderBook)
+
+	price := orderBook.Config.RoundToTick(req.Price)
+	if order.Limit != nil {
+		price = order.Limit.Price
+	}
+	return orderResult{200, map[string]any{
+		"msg":         localize(c, "order replaced"),
+		"order":       newOrderData(c, order, price),
+		"matches":     len(result.Matches),
+		"filled_size": order.FilledSize,
+	}, nil}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	Convey("When the public and admin routes are served apart", t, func() {
+		public, admin := echo.New(), echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(public.Group("/api/v1"))
+		ex.registerRoutes(public.Group("", deprecated(legacySunset, "/api/v1")))
+		ex.registerAdminRoutes(admin.Group(""))
+
+		serve := func(e *echo.Echo, path string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			return rec
+		}
+
+		Convey("Should only report latencies on the admin routes", func() {
+			So(serve(public, "/api/v1/latency").Code, ShouldEqual, http.StatusNotFound)
+			So(serve(public, "/latency").Code, ShouldEqual, http.StatusNotFound)
+
+			rec := serve(admin, "/latency")
+			var res struct {
+				Latency []usecase.LatencyReport `json:"latency"`
+			}
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(json.Unmarshal(rec.Body.Bytes(), &res), ShouldBeNil)
+		})
+	})
+}
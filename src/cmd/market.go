@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/labstack/echo/v4"
+)
+
+type Market string
+
+const (
+	MarketETH Market = "ETH"
+)
+
+// MarketInfo describes an instrument: what it trades, what it settles in and its trading parameters.
+type MarketInfo struct {
+	BaseAsset  string
+	QuoteAsset string
+	Config     entity.MarketConfig
+}
+
+var markets = map[Market]MarketInfo{
+	MarketETH: {
+		BaseAsset:  "ETH",
+		QuoteAsset: "USDT",
+		Config: entity.MarketConfig{
+			TickSize:      0.01,
+			LotSize:       0.0001,
+			MinNotional:   1,
+			MaxSweepDepth: 50,
+			PriceBandPct:  10,
+			HaltCooldown:  5 * time.Minute,
+		},
+	},
+}
+
+type MarketData struct {
+	Symbol          Market             `json:"symbol"`
+	BaseAsset       string             `json:"base_asset"`
+	QuoteAsset      string             `json:"quote_asset"`
+	SettlementAsset string             `json:"settlement_asset"`
+	TickSize        float64            `json:"tick_size"`
+	LotSize         float64            `json:"lot_size,omitempty"`
+	MinNotional     float64            `json:"min_notional,omitempty"`
+	MaxSweepDepth   int                `json:"max_sweep_depth"`
+	PriceBandPct    float64            `json:"price_band_pct,omitempty"`
+	State           entity.MarketState `json:"state"`
+	// IndicativePrice is the price an auction would uncross at now, if any orders cross
+	IndicativePrice float64 `json:"indicative_price,omitempty"`
+	// HaltedUntil is when a halted market resumes trading, in Unix milliseconds
+	HaltedUntil int64 `json:"halted_until,omitempty"`
+}
+
+func (ex *Exchange) handleGetMarket(c echo.Context) error {
+	market, _ := ex.symbols.Resolve(c.Param("symbol"))
+	info, exist := markets[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	data := MarketData{
+		Symbol:          market,
+		BaseAsset:       info.BaseAsset,
+		QuoteAsset:      info.QuoteAsset,
+		SettlementAsset: info.QuoteAsset,
+		TickSize:        info.Config.TickSize,
+		LotSize:         info.Config.LotSize,
+		MinNotional:     info.Config.MinNotional,
+		MaxSweepDepth:   info.Config.MaxSweepDepth,
+		PriceBandPct:    info.Config.PriceBandPct,
+	}
+	ex.engines[market].Do(func(orderBook *entity.OrderBook) {
+		data.State = orderBook.State()
+		if orderBook.InAuction() {
+			data.IndicativePrice, _, _ = orderBook.IndicativePrice()
+		}
+		if until, halted := orderBook.HaltedUntil(); halted {
+			data.HaltedUntil = until.UnixMilli()
+		}
+	})
+
+	return c.JSON(200, data)
+}
+
+// SymbolRegistry resolves the different spellings integrations use for a market ("ETH", "ETHUSDT",
+// "ETH-USDT", "eth/usdt", ...) to the market itself.
+type SymbolRegistry struct {
+	aliases map[string]Market
+}
+
+func NewSymbolRegistry(markets map[Market]MarketInfo) *SymbolRegistry {
+	r := &SymbolRegistry{aliases: map[string]Market{}}
+	for market, info := range markets {
+		r.Register(string(market), market)
+		r.Register(info.BaseAsset+info.QuoteAsset, market)
+	}
+	return r
+}
+
+// Register adds an alias for the market. Case and separators in the alias are ignored.
+func (r *SymbolRegistry) Register(alias string, market Market) {
+	r.aliases[normalizeSymbol(alias)] = market
+}
+
+func (r *SymbolRegistry) Resolve(symbol string) (Market, bool) {
+	market, exist := r.aliases[normalizeSymbol(symbol)]
+	return market, exist
+}
+
+var symbolSeparators = strings.NewReplacer("-", "", "/", "", "_", "", " ", "")
+
+func normalizeSymbol(symbol string) string {
+	// Path parameters keep "ETH%2FUSDT" escaped
+	if unescaped, err := url.PathUnescape(symbol); err == nil {
+		symbol = unescaped
+	}
+	return symbolSeparators.Replace(strings.ToUpper(symbol))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSymbolRegistry(t *testing.T) {
+	Convey("When resolving market symbols", t, func() {
+		r := NewSymbolRegistry(markets)
+
+		Convey("Should resolve every common spelling to the same market", func() {
+			for _, symbol := range []string{"ETH", "eth", "ETHUSDT", "ETH-USDT", "ETH/USDT", "eth_usdt", "ETH%2FUSDT"} {
+				market, exist := r.Resolve(symbol)
+				So(exist, ShouldBeTrue)
+				So(market, ShouldEqual, MarketETH)
+			}
+		})
+
+		Convey("Should resolve registered aliases", func() {
+			r.Register("ETH-PERP", MarketETH)
+
+			market, exist := r.Resolve("ethperp")
+			So(exist, ShouldBeTrue)
+			So(market, ShouldEqual, MarketETH)
+		})
+
+		Convey("Should not resolve unknown symbols", func() {
+			_, exist := r.Resolve("BTCUSDT")
+			So(exist, ShouldBeFalse)
+		})
+	})
+}
+
+func TestScheduleAuctions(t *testing.T) {
+	Convey("When scheduling auction windows", t, func() {
+		ex := NewExchange()
+
+		Convey("Should hold each market's windows", func() {
+			So(ex.scheduleAuctions("ETH@07:55-08:00, ethusdt@23:55-00:05"), ShouldBeNil)
+
+			So(ex.auctions[MarketETH].Windows, ShouldResemble, []usecase.AuctionWindow{
+				{Start: 7*time.Hour + 55*time.Minute, End: 8 * time.Hour},
+				{Start: 23*time.Hour + 55*time.Minute, End: 5 * time.Minute},
+			})
+		})
+
+		Convey("Should reject malformed windows and unknown markets", func() {
+			for _, spec := range []string{"ETH", "ETH@07:55", "ETH@7.55-08:00", "ETH@07:55-25:00", "BTC@07:55-08:00"} {
+				So(ex.scheduleAuctions(spec), ShouldNotBeNil)
+			}
+			So(ex.auctions, ShouldBeEmpty)
+		})
+	})
+}
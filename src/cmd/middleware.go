@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// legacySunset is the date after which the unversioned routes will be removed.
+var legacySunset = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks every response of the group with Deprecation and Sunset headers (RFC 8594)
+// and points clients to the same path under the successor prefix.
+func deprecated(sunset time.Time, successorPrefix string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			h := c.Response().Header()
+			h.Set("Deprecation", "true")
+			h.Set("Sunset", sunset.Format(http.TimeFormat))
+			h.Set("Link", "<"+successorPrefix+c.Request().URL.Path+`>; rel="successor-version"`)
+			return next(c)
+		}
+	}
+}
+
+// sensitiveFields are redacted from logged request/response bodies and query strings.
+var sensitiveFields = map[string]bool{
+	"api_key":    true,
+	"api_secret": true,
+	"secret":     true,
+	"signature":  true,
+	"password":   true,
+	"token":      true,
+}
+
+const redacted = "[REDACTED]"
+
+type bodyRecorder struct {
+	http.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// accessLog logs method, path, client, user, latency, status and truncated bodies of every request in the
+// group. Register it only on the route groups that need to be audited.
+func accessLog(maxBody int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			var reqBody []byte
+			if req.Body != nil {
+				reqBody, _ = io.ReadAll(req.Body)
+				req.Body = io.NopCloser(bytes.NewReader(reqBody))
+			}
+
+			resBody := new(bytes.Buffer)
+			c.Response().Writer = &bodyRecorder{ResponseWriter: c.Response().Writer, body: resBody}
+
+			start := time.Now()
+			err := next(c)
+			latency := time.Since(start)
+
+			c.Logger().Infoj(map[string]any{
+				"method":   req.Method,
+				"path":     req.URL.Path,
+				"query":    redactQuery(req.URL.Query()),
+				"client":   c.RealIP(),
+				"user":     requestUser(req.URL.Query(), reqBody),
+				"latency":  latency.String(),
+				"status":   c.Response().Status,
+				"request":  truncate(redactBody(reqBody), maxBody),
+				"response": truncate(redactBody(resBody.Bytes()), maxBody),
+			})
+
+			return err
+		}
+	}
+}
+
+// requestUser returns the user a request acts for: the user field of its JSON body, or else its user query
+// parameter, as DELETE /orders takes it. It's empty for requests that don't name one.
+func requestUser(query url.Values, body []byte) string {
+	var payload struct {
+		User string `json:"user"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.User != "" {
+		return payload.User
+	}
+	return query.Get("user")
+}
+
+func redactQuery(query url.Values) string {
+	for key := range query {
+		if sensitiveFields[strings.ToLower(key)] {
+			query[key] = []string{redacted}
+		}
+	}
+	return query.Encode()
+}
+
+// redactBody returns the JSON body with its sensitive fields redacted. Numbers are kept as they were sent.
+// Bodies that aren't JSON can't be checked for secrets, so they're redacted as a whole.
+func redactBody(body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+
+	var payload any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil || decoder.Decode(&struct{}{}) != io.EOF {
+		return redacted
+	}
+
+	redactedBody, err := json.Marshal(redactValue(payload))
+	if err != nil {
+		return redacted
+	}
+	return string(redactedBody)
+}
+
+func redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, field := range val {
+			if sensitiveFields[strings.ToLower(key)] {
+				val[key] = redacted
+				continue
+			}
+			val[key] = redactValue(field)
+		}
+	case []any:
+		for i := range val {
+			val[i] = redactValue(val[i])
+		}
+	}
+	return v
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "...(truncated)"
+}
+
+// timestampHeader carries the client's millisecond epoch time on signed requests.
+const timestampHeader = "X-Timestamp"
+
+// clockSkewGuard rejects requests whose timestamp header deviates from server time by more than maxSkew.
+// Requests without the header are passed through.
+func clockSkewGuard(maxSkew time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			raw := c.Request().Header.Get(timestampHeader)
+			if raw == "" {
+				return next(c)
+			}
+
+			ts, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]any{
+					"msg": localize(c, "invalid timestamp"),
+				})
+			}
+
+			skew := time.Since(time.UnixMilli(ts))
+			if skew > maxSkew || skew < -maxSkew {
+				return c.JSON(http.StatusBadRequest, map[string]any{
+					"msg":         localize(c, "timestamp outside of allowed clock skew"),
+					"server_time": time.Now().UnixMilli(),
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// recordLatency records how long the route's requests take as the operation, for the SLA latency report.
+func recordLatency(latency *usecase.LatencyTracker, operation string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			latency.Record(operation, time.Since(start))
+			return err
+		}
+	}
+}
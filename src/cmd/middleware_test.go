@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccessLog(t *testing.T) {
+	Convey("When requests go through the access log", t, func() {
+		e := echo.New()
+		output := new(bytes.Buffer)
+		e.Logger.SetOutput(output)
+		e.Logger.SetLevel(log.INFO)
+		e.POST("/echo", func(c echo.Context) error {
+			return c.JSONBlob(200, []byte(`{"token": "issued", "size": 0.10000000000000001}`))
+		}, accessLog(accessLogMaxBody))
+
+		logged := func(query, contentType, body string) map[string]any {
+			output.Reset()
+			req := httptest.NewRequest(http.MethodPost, "/echo"+query, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, contentType)
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			var entry map[string]any
+			So(json.Unmarshal(output.Bytes(), &entry), ShouldBeNil)
+			return entry
+		}
+
+		Convey("Should mask secrets in JSON bodies and keep the rest as sent", func() {
+			entry := logged("", echo.MIMEApplicationJSON, `{"user": "alice", "api_secret": "s3cr3t", "auth": {"Password": "hunter2"}, "price": 1234567890123456789}`)
+
+			So(entry["user"], ShouldEqual, "alice")
+			So(entry["request"], ShouldNotContainSubstring, "s3cr3t")
+			So(entry["request"], ShouldNotContainSubstring, "hunter2")
+			So(entry["request"], ShouldContainSubstring, `"price":1234567890123456789`)
+			So(entry["response"], ShouldEqual, `{"size":0.10000000000000001,"token":"[REDACTED]"}`)
+		})
+
+		Convey("Should mask secrets in the query", func() {
+			entry := logged("?user=bob&signature=abcdef&Token=xyz", echo.MIMEApplicationJSON, "")
+
+			So(entry["user"], ShouldEqual, "bob")
+			So(entry["query"], ShouldNotContainSubstring, "abcdef")
+			So(entry["query"], ShouldNotContainSubstring, "xyz")
+			So(entry["query"], ShouldContainSubstring, "user=bob")
+			So(entry["request"], ShouldEqual, "")
+		})
+
+		Convey("Should mask bodies that aren't JSON as a whole", func() {
+			for _, body := range []string{"api_key=abc&size=1", `{"size": 1} api_key=abc`} {
+				entry := logged("", echo.MIMEApplicationForm, body)
+
+				So(entry["request"], ShouldEqual, redacted)
+			}
+		})
+	})
+}
+
+func TestClockSkewGuard(t *testing.T) {
+	Convey("When signed requests carry a timestamp", t, func() {
+		e := echo.New()
+		e.GET("/signed", func(c echo.Context) error {
+			return c.NoContent(http.StatusNoContent)
+		}, clockSkewGuard(maxClockSkew))
+
+		serve := func(timestamp string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/signed", nil)
+			if timestamp != "" {
+				req.Header.Set(timestampHeader, timestamp)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+		millis := func(skew time.Duration) string {
+			return strconv.FormatInt(time.Now().Add(skew).UnixMilli(), 10)
+		}
+
+		Convey("Should pass requests without a timestamp", func() {
+			So(serve("").Code, ShouldEqual, http.StatusNoContent)
+		})
+
+		Convey("Should pass timestamps within the allowed skew either way", func() {
+			So(serve(millis(maxClockSkew/2)).Code, ShouldEqual, http.StatusNoContent)
+			So(serve(millis(-maxClockSkew/2)).Code, ShouldEqual, http.StatusNoContent)
+		})
+
+		Convey("Should reject malformed timestamps", func() {
+			rec := serve("yesterday")
+
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			So(rec.Body.String(), ShouldContainSubstring, "invalid timestamp")
+		})
+
+		Convey("Should reject timestamps beyond the allowed skew with the server time", func() {
+			for _, skew := range []time.Duration{2 * maxClockSkew, -2 * maxClockSkew} {
+				rec := serve(millis(skew))
+
+				var res struct {
+					Msg        string `json:"msg"`
+					ServerTime int64  `json:"server_time"`
+				}
+				So(json.Unmarshal(rec.Body.Bytes(), &res), ShouldBeNil)
+				So(rec.Code, ShouldEqual, http.StatusBadRequest)
+				So(res.Msg, ShouldEqual, "timestamp outside of allowed clock skew")
+				So(res.ServerTime, ShouldAlmostEqual, time.Now().UnixMilli(), 1000)
+			}
+		})
+	})
+}
+
+func TestDeprecated(t *testing.T) {
+	Convey("When the API is served under /api/v1 and the legacy unversioned paths", t, func() {
+		e := echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(e.Group("/api/v1"))
+		ex.registerRoutes(e.Group("", deprecated(legacySunset, "/api/v1")))
+
+		serve := func(path string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			return rec
+		}
+
+		Convey("Should mark legacy routes deprecated and point to their successor", func() {
+			rec := serve("/time")
+
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get("Deprecation"), ShouldEqual, "true")
+			So(rec.Header().Get("Sunset"), ShouldEqual, "Thu, 01 Apr 2027 00:00:00 GMT")
+			So(rec.Header().Get("Link"), ShouldEqual, `</api/v1/time>; rel="successor-version"`)
+		})
+
+		Convey("Should leave /api/v1 routes alone", func() {
+			rec := serve("/api/v1/time")
+
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get("Deprecation"), ShouldBeEmpty)
+			So(rec.Header().Get("Sunset"), ShouldBeEmpty)
+			So(rec.Header().Get("Link"), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+type PlaceOCOOrderRequest struct {
+	Market    Market                `json:"market"`
+	Placement entity.OrderPlacement `json:"placement"`
+	User      string                `json:"user"`
+	// Legs are the two linked orders, e.g. a take-profit limit and a stop-loss, placed in this order
+	Legs []OCOLegRequest `json:"legs"`
+}
+
+type OCOLegRequest struct {
+	Type      entity.OrderType `json:"type"`
+	Size      float64          `json:"size"`
+	Price     float64          `json:"price"`
+	StopPrice float64          `json:"stop_price"`
+}
+
+// handlePlaceOCOOrder places two linked orders where filling, triggering or cancelling one cancels the other.
+func (ex *Exchange) handlePlaceOCOOrder(c echo.Context) error {
+	var req PlaceOCOOrderRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return err
+	}
+
+	market, _ := ex.symbols.Resolve(string(req.Market))
+	engine, exist := ex.engines[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+	if len(req.Legs) != 2 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"msg":           localize(c, "an OCO order needs exactly two legs"),
+			"reject_reason": entity.RejectReasonInvalidOrder,
+		})
+	}
+
+	legs := make([]entity.OCOLeg, 0, 2)
+	for _, legReq := range req.Legs {
+		leg := entity.OCOLeg{
+			Type:  legReq.Type,
+			Price: legReq.Price,
+			Order: entity.NewOrder(req.Placement, legReq.Size),
+		}
+		leg.Order.Owner = req.User
+		if legReq.Type == entity.StopOrder || legReq.Type == entity.StopLimitOrder {
+			leg.Price = legReq.StopPrice
+			leg.Order.LimitPrice = legReq.Price
+		}
+		legs = append(legs, leg)
+	}
+
+	var result orderResult
+	engine.Do(func(orderBook *entity.OrderBook) {
+		applied, err := engine.Apply(orderBook, usecase.OCOCommand(legs[0], legs[1]), legs[0].Order, legs[1].Order)
+		if err != nil {
+			result = placeOrderError(c, err, "handlePlaceOCOOrder: failed to place OCO order")
+			return
+		}
+		ex.quality[market].RecordBook(orderBook)
+
+		orders := make([]*OrderData, 0, 2)
+		for i, leg := range legs {
+			price := leg.Order.LimitPrice
+			if leg.Type == entity.LimitOrder {
+				price = orderBook.Config.RoundToTick(req.Legs[i].Price)
+			}
+			orders = append(orders, newOrderData(c, leg.Order, price))
+		}
+
+		result = orderResult{200, map[string]any{
+			"msg":      localize(c, "order placed"),
+			"orders":   orders,
+			"matches":  len(applied.Matches),
+			"sequence": orderBook.MutationSequence(),
+		}, nil}
+	})
+	return result.respond(c)
+}
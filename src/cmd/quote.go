@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+type MassQuoteRequest struct {
+	Market Market `json:"market"`
+	User   string `json:"user"`
+	// Quotes replace all of the user's quotes in the market. An empty list pulls them.
+	Quotes []entity.Quote `json:"quotes"`
+}
+
+// handleMassQuote replaces a maker's two-sided quotes in a market in one request.
+func (ex *Exchange) handleMassQuote(c echo.Context) error {
+	var req MassQuoteRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return err
+	}
+
+	market, _ := ex.symbols.Resolve(string(req.Market))
+	engine, exist := ex.engines[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	var result orderResult
+	engine.Do(func(orderBook *entity.OrderBook) {
+		applied, err := engine.Apply(orderBook, usecase.Command{Type: usecase.CommandMassQuote, Owner: req.User, Quotes: req.Quotes})
+		reason, rejected := entity.RejectReasonOf(err)
+		if err != nil && (len(applied.Orders) == 0 || !rejected) {
+			result = placeOrderError(c, err, "handleMassQuote: failed to replace quotes")
+			return
+		}
+		ex.quality[market].RecordBook(orderBook)
+
+		orderData := make([]*OrderData, 0, len(applied.Orders))
+		for i, order := range applied.Orders {
+			orderData = append(orderData, newOrderData(c, order, orderBook.Config.RoundToTick(req.Quotes[i].Price)))
+		}
+		body := map[string]any{
+			"msg":      localize(c, "quotes replaced"),
+			"orders":   orderData,
+			"matches":  len(applied.Matches),
+			"sequence": orderBook.MutationSequence(),
+		}
+		if err != nil {
+			// The previous set is already cancelled and the quotes before the rejected one may have traded,
+			// so the client gets what was placed instead of a plain rejection.
+			body["msg"] = localize(c, "quotes partially replaced")
+			body["reject_reason"] = reason
+			result = orderResult{http.StatusConflict, body, nil}
+			return
+		}
+		result = orderResult{200, body, nil}
+	})
+	return result.respond(c)
+}
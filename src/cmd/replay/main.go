@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+)
+
+/*
+	replay rebuilds a market's book from the command log the exchange writes when started with
+	COMMAND_LOG_DIR, and prints its snapshot as JSON:
+
+	replay -log commands/ETH.log
+*/
+
+func main() {
+	logPath := flag.String("log", "", "command log of the market to replay")
+	flag.Parse()
+	if *logPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := replay(*logPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func replay(logPath string) error {
+	file, err := os.Open(logPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	commands, err := usecase.ReadCommands(file)
+	if err != nil {
+		return err
+	}
+	book, err := usecase.Replay(commands)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(book.Snapshot())
+}
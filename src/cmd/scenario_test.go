@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// scenario is a replayable sequence of API calls and expectations on one market, run end to end against a
+// fresh exchange. Orders are referred to by the names they were placed under.
+type scenario struct {
+	market Market
+	steps  []func(*scenarioRun)
+}
+
+// scenarioRun is the state of one run of a scenario.
+type scenarioRun struct {
+	e         *echo.Echo
+	ex        *Exchange
+	ids       map[string]int64
+	responses map[string]scenarioResponse
+}
+
+type scenarioResponse struct {
+	code         int
+	order        OrderData
+	orders       []OrderData
+	rejectReason entity.RejectReason
+}
+
+func newScenario(market Market) *scenario {
+	return &scenario{market: market}
+}
+
+// limitOrder places a limit order of the user.
+func (s *scenario) limitOrder(name, user string, placement entity.OrderPlacement, size, price float64) *scenario {
+	return s.place(name, PlaceOrderRequest{
+		Type:      entity.LimitOrder,
+		Placement: placement,
+		Size:      size,
+		Price:     price,
+		User:      user,
+	})
+}
+
+// marketOrder places a market order of the user.
+func (s *scenario) marketOrder(name, user string, placement entity.OrderPlacement, size float64) *scenario {
+	return s.place(name, PlaceOrderRequest{
+		Type:      entity.MarketOrder,
+		Placement: placement,
+		Size:      size,
+		User:      user,
+	})
+}
+
+func (s *scenario) place(name string, req PlaceOrderRequest) *scenario {
+	req.Market = s.market
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		body, _ := json.Marshal(req)
+		rec := r.serve(http.MethodPost, "/api/v1/order", string(body))
+
+		var res struct {
+			Order        OrderData           `json:"order"`
+			RejectReason entity.RejectReason `json:"reject_reason"`
+		}
+		So(json.Unmarshal(rec.Body.Bytes(), &res), ShouldBeNil)
+		r.ids[name] = res.Order.ID
+		r.responses[name] = scenarioResponse{code: rec.Code, order: res.Order, rejectReason: res.RejectReason}
+	})
+	return s
+}
+
+// massQuote replaces the user's quotes. The quotes placed are named after the set, suffixed by their index.
+func (s *scenario) massQuote(name, user string, quotes ...entity.Quote) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		body, _ := json.Marshal(MassQuoteRequest{Market: s.market, User: user, Quotes: quotes})
+		rec := r.serve(http.MethodPost, "/api/v1/quotes", string(body))
+
+		var res struct {
+			Orders       []OrderData         `json:"orders"`
+			RejectReason entity.RejectReason `json:"reject_reason"`
+		}
+		So(json.Unmarshal(rec.Body.Bytes(), &res), ShouldBeNil)
+		for i, order := range res.Orders {
+			r.ids[fmt.Sprintf("%s %d", name, i)] = order.ID
+		}
+		r.responses[name] = scenarioResponse{code: rec.Code, orders: res.Orders, rejectReason: res.RejectReason}
+	})
+	return s
+}
+
+// cancel cancels the named order and expects it to succeed.
+func (s *scenario) cancel(name string) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		rec := r.serve(http.MethodDelete, fmt.Sprintf("/api/v1/order/cancel/%d", r.ids[name]), "")
+		So(rec.Code, ShouldEqual, http.StatusOK)
+	})
+	return s
+}
+
+// startAuction puts the market in a call auction. Auctions have no API, so it goes through the engine.
+func (s *scenario) startAuction() *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		_, err := r.ex.engines[s.market].Submit(usecase.Command{Type: usecase.CommandStartAuction})
+		So(err, ShouldBeNil)
+	})
+	return s
+}
+
+// uncross ends the auction and expects it to clear at the price.
+func (s *scenario) uncross(clearingPrice float64) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		result, err := r.ex.engines[s.market].Submit(usecase.Command{Type: usecase.CommandUncross})
+		So(err, ShouldBeNil)
+		So(result.Matches, ShouldNotBeEmpty)
+		So(result.Matches[0].Price, ShouldEqual, clearingPrice)
+	})
+	return s
+}
+
+// expectPlaced expects the named order to have been accepted with the status and filled size it had once
+// placed.
+func (s *scenario) expectPlaced(name string, status entity.OrderStatus, filledSize float64) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		res := r.responses[name]
+		So(res.code, ShouldEqual, http.StatusOK)
+		So(res.order.Status, ShouldEqual, status)
+		So(res.order.FilledSize, ShouldEqual, filledSize)
+	})
+	return s
+}
+
+// expectRejected expects the named order to have been rejected for the reason.
+func (s *scenario) expectRejected(name string, reason entity.RejectReason) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		res := r.responses[name]
+		So(res.code, ShouldEqual, http.StatusBadRequest)
+		So(res.rejectReason, ShouldEqual, reason)
+	})
+	return s
+}
+
+// expectQuotesPartiallyPlaced expects the named quote set to have stopped at a quote rejected for the reason,
+// with the quotes before it reported as placed.
+func (s *scenario) expectQuotesPartiallyPlaced(name string, placed int, reason entity.RejectReason) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		res := r.responses[name]
+		So(res.code, ShouldEqual, http.StatusConflict)
+		So(res.rejectReason, ShouldEqual, reason)
+		So(res.orders, ShouldHaveLength, placed+1)
+		So(res.orders[placed].Status, ShouldEqual, entity.OrderStatusRejected)
+	})
+	return s
+}
+
+// expectResting expects the named order in the book with the remaining and filled sizes.
+func (s *scenario) expectResting(name string, size, filledSize float64) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		order := r.restingOrder(s.market, name)
+		So(order, ShouldNotBeNil)
+		So(order.Size, ShouldEqual, size)
+		So(order.FilledSize, ShouldEqual, filledSize)
+	})
+	return s
+}
+
+// expectGone expects the named order to no longer be in the book.
+func (s *scenario) expectGone(name string) *scenario {
+	s.steps = append(s.steps, func(r *scenarioRun) {
+		So(r.restingOrder(s.market, name), ShouldBeNil)
+	})
+	return s
+}
+
+// run replays the scenario against a fresh exchange. It must be called inside a Convey block.
+func (s *scenario) run() {
+	e := echo.New()
+	ex := NewExchange()
+	ex.registerRoutes(e.Group("/api/v1"))
+	for _, engine := range ex.engines {
+		engine.Start()
+		defer engine.Stop()
+	}
+
+	r := &scenarioRun{
+		e:         e,
+		ex:        ex,
+		ids:       map[string]int64{},
+		responses: map[string]scenarioResponse{},
+	}
+	for _, step := range s.steps {
+		step(r)
+	}
+}
+
+func (r *scenarioRun) serve(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func (r *scenarioRun) restingOrder(market Market, name string) *OrderData {
+	rec := r.serve(http.MethodGet, fmt.Sprintf("/api/v1/book/%s", market), "")
+	var book OrderBookData
+	So(json.Unmarshal(rec.Body.Bytes(), &book), ShouldBeNil)
+
+	for _, order := range append(book.Bids, book.Asks...) {
+		if order.ID == r.ids[name] {
+			return order
+		}
+	}
+	return nil
+}
+
+func TestScenarios(t *testing.T) {
+	Convey("When a market sell meets a larger resting bid", t, func() {
+		newScenario(MarketETH).
+			limitOrder("alice bid", "alice", entity.BID_ORDER, 10, 100).
+			marketOrder("bob sell", "bob", entity.ASK_ORDER, 5).
+			expectPlaced("bob sell", entity.OrderStatusFilled, 5).
+			expectResting("alice bid", 5, 5).
+			run()
+	})
+
+	Convey("When a market sell sweeps bids at several prices", t, func() {
+		newScenario(MarketETH).
+			limitOrder("alice bid", "alice", entity.BID_ORDER, 5, 100).
+			limitOrder("carol bid", "carol", entity.BID_ORDER, 5, 100).
+			limitOrder("dave bid", "dave", entity.BID_ORDER, 5, 101).
+			marketOrder("bob sell", "bob", entity.ASK_ORDER, 8).
+			expectPlaced("bob sell", entity.OrderStatusFilled, 8).
+			expectGone("dave bid").
+			expectResting("alice bid", 2, 3).
+			expectResting("carol bid", 5, 0).
+			run()
+	})
+
+	Convey("When the only resting order is cancelled before a market order", t, func() {
+		newScenario(MarketETH).
+			limitOrder("alice ask", "alice", entity.ASK_ORDER, 5, 100).
+			expectPlaced("alice ask", entity.OrderStatusNew, 0).
+			cancel("alice ask").
+			expectGone("alice ask").
+			marketOrder("bob buy", "bob", entity.BID_ORDER, 1).
+			expectRejected("bob buy", entity.RejectReasonInsufficientLiquidity).
+			run()
+	})
+
+	Convey("When crossing orders are collected in an auction", t, func() {
+		newScenario(MarketETH).
+			startAuction().
+			limitOrder("alice bid", "alice", entity.BID_ORDER, 3, 101).
+			limitOrder("bob ask", "bob", entity.ASK_ORDER, 2, 99).
+			limitOrder("carol ask", "carol", entity.ASK_ORDER, 4, 100).
+			expectPlaced("alice bid", entity.OrderStatusNew, 0).
+			marketOrder("dave buy", "dave", entity.BID_ORDER, 1).
+			expectRejected("dave buy", entity.RejectReasonAuctionInProgress).
+			uncross(100.5).
+			expectGone("alice bid").
+			expectGone("bob ask").
+			expectResting("carol ask", 3, 1).
+			run()
+	})
+
+	Convey("When a mass quote's first quote trades and moves the price band past a later one", t, func() {
+		newScenario(MarketETH).
+			limitOrder("alice ask", "alice", entity.ASK_ORDER, 1, 100).
+			limitOrder("bob bid", "bob", entity.BID_ORDER, 1, 100).
+			limitOrder("carol ask", "carol", entity.ASK_ORDER, 1, 109).
+			massQuote("maker quotes", "maker",
+				entity.Quote{Placement: entity.BID_ORDER, Price: 109, Size: 1},
+				entity.Quote{Placement: entity.ASK_ORDER, Price: 95, Size: 1},
+			).
+			expectQuotesPartiallyPlaced("maker quotes", 1, entity.RejectReasonPriceOutOfBand).
+			expectGone("carol ask").
+			run()
+	})
+
+	Convey("When orders have a non-positive size", t, func() {
+		newScenario(MarketETH).
+			limitOrder("alice ask", "alice", entity.ASK_ORDER, 5, 100).
+			marketOrder("bob buy", "bob", entity.BID_ORDER, 0).
+			marketOrder("carol buy", "carol", entity.BID_ORDER, -3).
+			limitOrder("dave bid", "dave", entity.BID_ORDER, -1, 100).
+			expectRejected("bob buy", entity.RejectReasonSizeTooSmall).
+			expectRejected("carol buy", entity.RejectReasonSizeTooSmall).
+			expectRejected("dave bid", entity.RejectReasonSizeTooSmall).
+			expectResting("alice ask", 5, 0).
+			run()
+	})
+
+	Convey("When orders don't align with the market's increments", t, func() {
+		newScenario(MarketETH).
+			limitOrder("alice bid", "alice", entity.BID_ORDER, 1, 100.005).
+			limitOrder("bob bid", "bob", entity.BID_ORDER, 0.00005, 100).
+			limitOrder("carol bid", "carol", entity.BID_ORDER, 0.001, 100).
+			marketOrder("dave buy", "dave", entity.BID_ORDER, 0.00005).
+			expectRejected("alice bid", entity.RejectReasonInvalidTickSize).
+			expectRejected("bob bid", entity.RejectReasonInvalidLotSize).
+			expectRejected("carol bid", entity.RejectReasonBelowMinNotional).
+			expectRejected("dave buy", entity.RejectReasonInvalidLotSize).
+			run()
+	})
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var soakDuration = flag.Duration("soak", 0, "run the soak test for this long, e.g. -soak=2h -timeout=0")
+
+const (
+	soakWorkers      = 8
+	soakCheckEvery   = 5 * time.Second
+	soakMaxLiveOrder = 2000
+	// soakTWAPEvery spaces each worker's TWAP orders: finished ones are kept for the scheduler's retention, so
+	// the TWAP orders reported on plateau at a worker's TWAP rate times that.
+	soakTWAPEvery = 5 * time.Second
+	// soakHeapSlack is how much the live heap may grow past its size after the first check before the soak
+	// test reports a leak. The flow keeps the number of resting orders bounded, so the heap should plateau.
+	soakHeapSlack = 64 << 20
+)
+
+// TestSoak drives random order flow through the whole stack for -soak, with the matching engines, expiry
+// sweepers and TWAP scheduler running as in production, and checks the books' invariants, heap growth and
+// that finished TWAP orders are dropped along the way.
+// It's skipped unless -soak is set; run it with -timeout=0.
+func TestSoak(t *testing.T) {
+	if *soakDuration == 0 {
+		t.Skip("set -soak to run the soak test")
+	}
+
+	Convey("When running random order flow for a long time", t, func() {
+		e := echo.New()
+		ex := NewExchange()
+		ex.registerRoutes(e.Group("/api/v1"))
+		for _, engine := range ex.engines {
+			engine.Start()
+		}
+		for _, sweeper := range ex.sweepers {
+			sweeper.Start()
+		}
+		ex.twap.Start()
+		defer func() {
+			ex.twap.Stop()
+			for _, sweeper := range ex.sweepers {
+				sweeper.Stop()
+			}
+			for _, engine := range ex.engines {
+				engine.Stop()
+			}
+		}()
+
+		markets := make([]Market, 0, len(ex.engines))
+		for market := range ex.engines {
+			markets = append(markets, market)
+		}
+
+		twaps := &soakTWAPs{placed: map[int64]time.Time{}}
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < soakWorkers; i++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				soakFlow(e, markets, fmt.Sprintf("soak-%d", seed), twaps, rand.New(rand.NewSource(seed)), stop)
+			}(int64(i))
+		}
+
+		deadline := time.After(*soakDuration)
+		ticker := time.NewTicker(soakCheckEvery)
+		defer ticker.Stop()
+
+		baseline := uint64(0)
+		violations := []string{}
+	soak:
+		for {
+			select {
+			case <-deadline:
+				break soak
+			case <-ticker.C:
+				violations = append(violations, checkBookInvariants(ex)...)
+				violations = append(violations, twaps.checkDropped(ex.twap)...)
+
+				heap := liveHeap()
+				if baseline == 0 {
+					baseline = heap
+				} else if heap > baseline+soakHeapSlack {
+					violations = append(violations, fmt.Sprintf("live heap grew from %d to %d bytes", baseline, heap))
+				}
+				if len(violations) > 0 {
+					break soak
+				}
+			}
+		}
+		close(stop)
+		wg.Wait()
+
+		Convey("Should keep every book consistent without leaking memory", func() {
+			So(violations, ShouldBeEmpty)
+			So(checkBookInvariants(ex), ShouldBeEmpty)
+		})
+	})
+}
+
+// soakFlow places limit, market, stop, good-till-date, pegged, bracket, OCO and TWAP orders and mass quotes
+// of the user around a fixed mid price and cancels some of its orders, until stopped. Once more orders than
+// its share of soakMaxLiveOrder were placed, it cancels all of the user's orders to keep the books bounded.
+func soakFlow(e *echo.Echo, markets []Market, user string, twaps *soakTWAPs, random *rand.Rand, stop <-chan struct{}) {
+	serve := func(method, path, body string) []byte {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Body.Bytes()
+	}
+
+	live := []int64{}
+	nextTWAP := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		market := markets[random.Intn(len(markets))]
+		placement, sign := "BID", -1.0
+		if random.Intn(2) == 0 {
+			placement, sign = "ASK", 1.0
+		}
+		size := 0.01 * float64(1+random.Intn(500))
+		// Every price, exits included, stays within 5% of 100, so market orders bounded by their slippage
+		// never reach the edge of the price band and halt the market for its cooldown
+		price := 100 + float64(random.Intn(60)-30)/10
+
+		path, body := "/api/v1/order", ""
+		switch n := random.Intn(100); {
+		case n < 40:
+			body = fmt.Sprintf(`{"type": "LIMIT_ORDER", "placement": %q, "size": %v, "price": %v, "market": %q, "user": %q}`, placement, size, price, market, user)
+		case n < 52:
+			body = fmt.Sprintf(`{"type": "MARKET_ORDER", "placement": %q, "size": %v, "allow_partial_fill": true, "max_slippage_pct": 0.5, "market": %q, "user": %q}`, placement, size, market, user)
+		case n < 60:
+			body = fmt.Sprintf(`{"type": "STOP_LIMIT_ORDER", "placement": %q, "size": %v, "stop_price": %v, "price": %v, "market": %q, "user": %q}`, placement, size, price, price-0.5*sign, market, user)
+		case n < 68:
+			expiresAt := time.Now().Add(time.Duration(1+random.Intn(3)) * time.Second).UnixMilli()
+			body = fmt.Sprintf(`{"type": "LIMIT_ORDER", "placement": %q, "size": %v, "price": %v, "expires_at": %d, "market": %q, "user": %q}`, placement, size, price, expiresAt, market, user)
+		case n < 73:
+			body = fmt.Sprintf(`{"type": "LIMIT_ORDER", "placement": %q, "size": %v, "price": %v, "peg_offset": %v, "market": %q, "user": %q}`, placement, size, price, sign*0.1, market, user)
+		case n < 78:
+			body = fmt.Sprintf(`{"type": "LIMIT_ORDER", "placement": %q, "size": %v, "price": %v, "bracket": {"take_profit_price": %v, "stop_loss_price": %v, "stop_loss_limit_price": %v}, "market": %q, "user": %q}`,
+				placement, size, price, price-sign, price+sign, price+1.5*sign, market, user)
+		case n < 83:
+			path = "/api/v1/order/oco"
+			body = fmt.Sprintf(`{"placement": %q, "legs": [{"type": "LIMIT_ORDER", "size": %v, "price": %v}, {"type": "STOP_LIMIT_ORDER", "size": %v, "stop_price": %v, "price": %v}], "market": %q, "user": %q}`,
+				placement, size, price+sign, size, price-sign, price-1.5*sign, market, user)
+		case n < 88:
+			path = "/api/v1/quotes"
+			body = fmt.Sprintf(`{"quotes": [{"placement": "BID", "price": %v, "size": %v}, {"placement": "ASK", "price": %v, "size": %v}], "market": %q, "user": %q}`,
+				price-0.5, size, price+0.5, size, market, user)
+		case n < 90:
+			if time.Now().Before(nextTWAP) {
+				continue
+			}
+			nextTWAP = time.Now().Add(soakTWAPEvery)
+			path = "/api/v1/algo/twap"
+			body = fmt.Sprintf(`{"placement": %q, "size": %v, "price": %v, "slices": %d, "interval_ms": 100, "market": %q, "user": %q}`, placement, size, price, 1+random.Intn(5), market, user)
+		default:
+			if len(live) > 0 {
+				i := random.Intn(len(live))
+				serve(http.MethodDelete, fmt.Sprintf("/api/v1/order/cancel/%d", live[i]), "")
+				live = append(live[:i], live[i+1:]...)
+			}
+			continue
+		}
+
+		var res struct {
+			Order  OrderData          `json:"order"`
+			Orders []OrderData        `json:"orders"`
+			TWAP   usecase.TWAPReport `json:"twap"`
+		}
+		json.Unmarshal(serve(http.MethodPost, path, body), &res)
+		if res.TWAP.ID != 0 {
+			twaps.add(res.TWAP.ID)
+		}
+		for _, order := range append(res.Orders, res.Order) {
+			if order.ID != 0 && !order.Status.IsTerminal() {
+				live = append(live, order.ID)
+			}
+		}
+		if len(live) > soakMaxLiveOrder/soakWorkers {
+			serve(http.MethodDelete, "/api/v1/orders?user="+user, "")
+			live = live[:0]
+		}
+	}
+}
+
+// soakTWAPs are the TWAP orders placed by the soak flow, to check the scheduler drops them once finished.
+type soakTWAPs struct {
+	mu     sync.Mutex
+	placed map[int64]time.Time
+}
+
+func (t *soakTWAPs) add(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.placed[id] = time.Now()
+}
+
+// checkDropped returns a description of every TWAP order the scheduler still reports past its retention, and
+// forgets the ones old enough to have been dropped.
+func (t *soakTWAPs) checkDropped(scheduler *usecase.TWAPScheduler) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	violations := []string{}
+	for id, placedAt := range t.placed {
+		// The soak flow's TWAP orders finish within a second of being placed
+		if time.Since(placedAt) < scheduler.Retention+2*time.Second {
+			continue
+		}
+		if _, exists := scheduler.Report(id); exists {
+			violations = append(violations, fmt.Sprintf("TWAP order %d is kept %v after it was placed", id, time.Since(placedAt)))
+		}
+		delete(t.placed, id)
+	}
+	return violations
+}
+
+// checkBookInvariants returns a description of every broken invariant across the books.
+func checkBookInvariants(ex *Exchange) []string {
+	violations := []string{}
+	for market, engine := range ex.engines {
+		engine.Do(func(orderBook *entity.OrderBook) {
+			live := orderBook.OrderCount() + len(orderBook.StopOrders())
+			if size := orderBook.OrderIndexSize(); size != live {
+				violations = append(violations, fmt.Sprintf("%s: %d orders indexed for %d live orders", market, size, live))
+			}
+
+			bids, asks := orderBook.Bids(), orderBook.Asks()
+			if len(bids) > 0 && len(asks) > 0 && bids[0].Price >= asks[0].Price {
+				violations = append(violations, fmt.Sprintf("%s: crossed book at %v / %v", market, bids[0].Price, asks[0].Price))
+			}
+			for _, limit := range append(bids, asks...) {
+				volume := 0.0
+				for _, order := range limit.Orders.All() {
+					volume = entity.RoundSize(volume + order.Size)
+					if order.Status.IsTerminal() {
+						violations = append(violations, fmt.Sprintf("%s: %s order %d resting at %v", market, order.Status, order.ID, limit.Price))
+					}
+				}
+				if limit.IsEmpty() || volume != limit.TotalVolume {
+					violations = append(violations, fmt.Sprintf("%s: level %v holds %v but reports %v", market, limit.Price, volume, limit.TotalVolume))
+				}
+			}
+		})
+	}
+	return violations
+}
+
+// liveHeap returns the bytes of heap still reachable after a garbage collection.
+func liveHeap() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// isoTimeLayout is ISO-8601 in UTC with millisecond precision, matching the ms epoch timestamps.
+const isoTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// apiTime converts t to the millisecond epoch used by every API timestamp. When the request has
+// ?time_format=iso, the ISO-8601 representation is returned too, otherwise iso is empty.
+func apiTime(c echo.Context, t time.Time) (ms int64, iso string) {
+	if c.QueryParam("time_format") == "iso" {
+		iso = t.UTC().Format(isoTimeLayout)
+	}
+	return t.UnixMilli(), iso
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+type PlaceTWAPOrderRequest struct {
+	Market    Market                `json:"market"`
+	Placement entity.OrderPlacement `json:"placement"`
+	User      string                `json:"user"`
+	Size      float64               `json:"size"`
+	// Price makes the slices limit orders at this price, market orders when zero
+	Price  float64 `json:"price"`
+	Slices int     `json:"slices"`
+	// IntervalMs is the time between slices in milliseconds
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// handlePlaceTWAPOrder schedules a parent order executed in slices spread over time.
+func (ex *Exchange) handlePlaceTWAPOrder(c echo.Context) error {
+	var req PlaceTWAPOrderRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return err
+	}
+
+	market, _ := ex.symbols.Resolve(string(req.Market))
+	engine, exist := ex.engines[market]
+	if !exist {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "market not found"),
+		})
+	}
+
+	algo := &usecase.TWAPOrder{
+		Engine:     engine,
+		Placement:  req.Placement,
+		Owner:      req.User,
+		Size:       req.Size,
+		LimitPrice: req.Price,
+		Slices:     req.Slices,
+		Interval:   time.Duration(req.IntervalMs) * time.Millisecond,
+	}
+	if err := ex.twap.Submit(algo); err != nil {
+		c.Logger().Warnf("handlePlaceTWAPOrder: %v", err)
+		reason, rejected := entity.RejectReasonOf(err)
+		if !rejected {
+			reason = entity.RejectReasonInvalidOrder
+		}
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"msg":           localize(c, "order rejected"),
+			"reject_reason": reason,
+		})
+	}
+
+	report, _ := ex.twap.Report(algo.ID)
+	return c.JSON(200, map[string]any{
+		"msg":  localize(c, "order placed"),
+		"twap": report,
+	})
+}
+
+// handleGetTWAPOrder reports the progress of a TWAP order: slices sent and size filled.
+func (ex *Exchange) handleGetTWAPOrder(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"msg": localize(c, "invalid order_id"),
+		})
+	}
+
+	report, exists := ex.twap.Report(id)
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"msg": localize(c, "order id not found"),
+		})
+	}
+
+	return c.JSON(200, report)
+}
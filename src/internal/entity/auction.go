@@ -0,0 +1,134 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StartAuction puts the book in a call auction: limit orders rest without matching, even when they cross,
+// and market orders are rejected until Uncross ends it.
+func (ob *OrderBook) StartAuction() {
+	ob.auction = true
+}
+
+// InAuction reports whether the book is collecting orders for a call auction.
+func (ob *OrderBook) InAuction() bool {
+	return ob.auction
+}
+
+// IndicativePrice returns the price the auction would uncross at now and the volume it would execute, or
+// false if no orders cross.
+func (ob *OrderBook) IndicativePrice() (price, volume float64, ok bool) {
+	ticks, volume, ok := ob.clearingPrice()
+	return ob.Config.FromTicks(ticks), volume, ok
+}
+
+// Uncross ends the auction and fills every crossing order at a single clearing price, in price-time
+// priority. The clearing price executes the most volume, then leaves the smallest surplus on either side,
+// then is the one nearest the last trade price, or the middle of the candidates for a market that never
+// traded. Resting orders cross regardless of self-trade prevention, which only guards incoming orders.
+func (ob *OrderBook) Uncross() ([]Match, error) {
+	if !ob.auction {
+		return nil, fmt.Errorf("Uncross: %s isn't in an auction", ob.Market)
+	}
+	ob.auction = false
+
+	matches := []Match{}
+	ticks, remaining, ok := ob.clearingPrice()
+	price := ob.Config.FromTicks(ticks)
+	for ok && remaining > 0 {
+		bid, ask := ob.bestLimit(BID_ORDER), ob.bestLimit(ASK_ORDER)
+		// Orders leaving the book with their OCO sibling can shrink the crossing volume
+		if bid == nil || ask == nil || !ob.crosses(BID_ORDER, bid.Price, price) || !ob.crosses(ASK_ORDER, ask.Price, price) {
+			break
+		}
+
+		bidOrder, askOrder := bid.Orders.Front(), ask.Orders.Front()
+		match := bid.fillOrder(bidOrder, askOrder)
+		ask.TotalVolume = subSize(ask.TotalVolume, match.SizeFilled)
+		ask.changed()
+		match.Price = price
+		remaining = subSize(remaining, match.SizeFilled)
+
+		ob.mutationSequence++
+		match.Sequence = ob.mutationSequence
+		ob.lastTradePrice = price
+		ob.emitMatch(match)
+		matches = append(matches, match)
+
+		if bidOrder.IsFilled() {
+			bid.DeleteOrder(bidOrder)
+		}
+		if askOrder.IsFilled() {
+			ask.DeleteOrder(askOrder)
+		}
+		if bid.IsEmpty() {
+			ob.deleteLimit(BID_ORDER, bid)
+		}
+		if ask.IsEmpty() {
+			ob.deleteLimit(ASK_ORDER, ask)
+		}
+	}
+
+	ob.triggerStops()
+	ob.springBrackets()
+	ob.notifyBBO()
+	return matches, nil
+}
+
+// clearingPrice returns the price in ticks the crossing orders would uncross at and the volume executed
+// there, or false if no orders cross. Only the prices of resting levels are candidates, but every price
+// between the best candidates executes the same volume, so the reference price may fall between them.
+func (ob *OrderBook) clearingPrice() (ticks int64, volume float64, ok bool) {
+	asks, bids := ob.asks.all(), ob.bids.all()
+	candidates := make([]int64, 0, len(asks)+len(bids))
+	for _, limit := range asks {
+		candidates = append(candidates, ob.Config.ToTicks(limit.Price))
+	}
+	for _, limit := range bids {
+		candidates = append(candidates, ob.Config.ToTicks(limit.Price))
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	// Ask volume at or below and bid volume at or above each candidate
+	askVolume := make([]float64, len(candidates))
+	cumulative := 0.0
+	for i, j := 0, 0; i < len(candidates); i++ {
+		for ; j < len(asks) && ob.Config.ToTicks(asks[j].Price) <= candidates[i]; j++ {
+			cumulative = addSize(cumulative, asks[j].TotalVolume)
+		}
+		askVolume[i] = cumulative
+	}
+	bidVolume := make([]float64, len(candidates))
+	cumulative = 0.0
+	for i, j := len(candidates)-1, 0; i >= 0; i-- {
+		for ; j < len(bids) && ob.Config.ToTicks(bids[j].Price) >= candidates[i]; j++ {
+			cumulative = addSize(cumulative, bids[j].TotalVolume)
+		}
+		bidVolume[i] = cumulative
+	}
+
+	var surplus float64
+	var lowest, highest int64
+	for i, candidate := range candidates {
+		executable := min(askVolume[i], bidVolume[i])
+		imbalance := subSize(max(askVolume[i], bidVolume[i]), executable)
+		switch {
+		case executable == 0 || executable < volume:
+			continue
+		case executable > volume || imbalance < surplus:
+			volume, surplus, lowest, highest = executable, imbalance, candidate, candidate
+		case imbalance == surplus:
+			highest = candidate
+		}
+	}
+	if volume == 0 {
+		return 0, 0, false
+	}
+
+	ticks = (lowest + highest) / 2
+	if ob.lastTradePrice > 0 {
+		ticks = min(max(ob.Config.ToTicks(ob.lastTradePrice), lowest), highest)
+	}
+	return ticks, volume, true
+}
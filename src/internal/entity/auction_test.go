@@ -0,0 +1,105 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAuction(t *testing.T) {
+	Convey("When a book collects orders for an auction", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.StartAuction()
+
+		bestBid := entity.NewOrder(entity.BID_ORDER, 3)
+		ob.PlaceLimitOrder(101, bestBid)
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.BID_ORDER, 2))
+		bestAsk := entity.NewOrder(entity.ASK_ORDER, 2)
+		ob.PlaceLimitOrder(99, bestAsk)
+		lastAsk := entity.NewOrder(entity.ASK_ORDER, 4)
+		ob.PlaceLimitOrder(100, lastAsk)
+
+		Convey("Should rest crossing limit orders without matching", func() {
+			So(ob.InAuction(), ShouldBeTrue)
+			So(ob.OrderCount(), ShouldEqual, 4)
+			So(bestBid.Status, ShouldEqual, entity.OrderStatusNew)
+		})
+
+		Convey("Should reject market orders", func() {
+			_, err := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonAuctionInProgress)
+		})
+
+		Convey("Should indicate the price executing the most volume", func() {
+			price, volume, ok := ob.IndicativePrice()
+			So(ok, ShouldBeTrue)
+			So(price, ShouldEqual, 100)
+			So(volume, ShouldEqual, 5)
+		})
+
+		Convey("Should fill every crossing order at the clearing price on uncrossing", func() {
+			matches, err := ob.Uncross()
+			So(err, ShouldBeNil)
+			So(matches, ShouldHaveLength, 3)
+			for _, match := range matches {
+				So(match.Price, ShouldEqual, 100)
+			}
+			So(bestBid.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(bestAsk.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(lastAsk.Size, ShouldEqual, 1)
+			So(ob.Bids(), ShouldBeEmpty)
+			So(ob.InAuction(), ShouldBeFalse)
+
+			_, err = ob.Uncross()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Should match orders again once uncrossed", func() {
+			ob.Uncross()
+			matches, _ := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+			So(matches, ShouldHaveLength, 1)
+			So(lastAsk.Status, ShouldEqual, entity.OrderStatusFilled)
+		})
+	})
+
+	Convey("When the best clearing prices tie", t, func() {
+		Convey("Should uncross a new market at their midpoint", func() {
+			ob := entity.NewOrderBook("test")
+			ob.StartAuction()
+			ob.PlaceLimitOrder(101, entity.NewOrder(entity.BID_ORDER, 1))
+			ob.PlaceLimitOrder(99, entity.NewOrder(entity.ASK_ORDER, 1))
+
+			matches, _ := ob.Uncross()
+			So(matches, ShouldHaveLength, 1)
+			So(matches[0].Price, ShouldEqual, 100)
+		})
+
+		Convey("Should uncross nearest the last trade price", func() {
+			ob := entity.RestoreOrderBook(entity.BookSnapshot{Market: "test", LastTradePrice: 105, Auction: true}, entity.DefaultMarketConfig)
+			ob.PlaceLimitOrder(101, entity.NewOrder(entity.BID_ORDER, 1))
+			ob.PlaceLimitOrder(99, entity.NewOrder(entity.ASK_ORDER, 1))
+
+			matches, _ := ob.Uncross()
+			So(matches, ShouldHaveLength, 1)
+			So(matches[0].Price, ShouldEqual, 101)
+		})
+	})
+
+	Convey("When no orders cross", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.StartAuction()
+		ob.PlaceLimitOrder(99, entity.NewOrder(entity.BID_ORDER, 1))
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 1))
+
+		Convey("Should end the auction without matching", func() {
+			_, _, ok := ob.IndicativePrice()
+			So(ok, ShouldBeFalse)
+			matches, err := ob.Uncross()
+			So(err, ShouldBeNil)
+			So(matches, ShouldBeEmpty)
+			So(ob.OrderCount(), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package entity
+
+/*
+	A bracket is an entry order carrying its exits: once the entry completes, the book springs a take-profit
+	limit and a stop-loss stop order on the opposite side as an OCO pair, sized to what the entry filled.
+	An entry that is cancelled or expires after filling partially springs its exits for the filled part.
+*/
+
+// Bracket holds the exit prices of an entry order, and the IDs of the exits once they're sprung.
+type Bracket struct {
+	TakeProfitPrice float64 `json:"take_profit_price"`
+	StopLossPrice   float64 `json:"stop_loss_price"`
+	// StopLossLimitPrice makes the stop-loss a stop-limit order at this price instead of a stop order
+	StopLossLimitPrice float64 `json:"stop_loss_limit_price,omitempty"`
+
+	TakeProfitOrderID int64 `json:"take_profit_order_id,omitempty"`
+	StopLossOrderID   int64 `json:"stop_loss_order_id,omitempty"`
+}
+
+func (b *Bracket) clone() *Bracket {
+	if b == nil {
+		return nil
+	}
+	clone := *b
+	return &clone
+}
+
+// PlaceBracketOrder places a limit or market entry order that springs the bracket's take-profit and
+// stop-loss once it completes. The take-profit must be on the profitable side of the stop-loss.
+func (ob *OrderBook) PlaceBracketOrder(orderType OrderType, price float64, order *Order, bracket Bracket) ([]Match, error) {
+	if orderType != LimitOrder && orderType != MarketOrder {
+		ob.accept(order)
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceBracketOrder: invalid entry order type %q", orderType)
+	}
+	if bracket.TakeProfitPrice <= 0 || bracket.StopLossPrice <= 0 || bracket.StopLossLimitPrice < 0 {
+		ob.accept(order)
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceBracketOrder: invalid take-profit %.2f or stop-loss %.2f", bracket.TakeProfitPrice, bracket.StopLossPrice)
+	}
+	takeProfit, stopLoss := ob.Config.ToTicks(bracket.TakeProfitPrice), ob.Config.ToTicks(bracket.StopLossPrice)
+	if (order.OrderPlacement == BID_ORDER && takeProfit <= stopLoss) || (order.OrderPlacement == ASK_ORDER && takeProfit >= stopLoss) {
+		ob.accept(order)
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceBracketOrder: take-profit %.2f is on the wrong side of stop-loss %.2f", bracket.TakeProfitPrice, bracket.StopLossPrice)
+	}
+
+	order.Bracket = &bracket
+	return ob.Place(orderType, price, order)
+}
+
+// springBrackets places the exits of the entries that completed since the last call. Entries complete
+// while levels are being filled, so their exits are held back until the operation that completed them is
+// done, like triggered stops.
+func (ob *OrderBook) springBrackets() {
+	if ob.sweeping || ob.springingBrackets {
+		return
+	}
+	ob.springingBrackets = true
+	defer func() { ob.springingBrackets = false }()
+
+	for len(ob.pendingBrackets) > 0 {
+		entry := ob.pendingBrackets[0]
+		ob.pendingBrackets[0] = nil
+		ob.pendingBrackets = ob.pendingBrackets[1:]
+
+		ob.springBracket(entry)
+	}
+}
+
+// springBracket places the entry's exits as an OCO pair for the size it filled.
+func (ob *OrderBook) springBracket(entry *Order) {
+	bracket := entry.Bracket
+	placement := entry.OrderPlacement.Opposite()
+
+	takeProfit := ob.newOrder(placement, entry.FilledSize)
+	takeProfit.Owner = entry.Owner
+	takeProfit.ParentOrderID = entry.ID
+	stopLoss := ob.newOrder(placement, entry.FilledSize)
+	stopLoss.Owner = entry.Owner
+	stopLoss.ParentOrderID = entry.ID
+	bracket.TakeProfitOrderID, bracket.StopLossOrderID = takeProfit.ID, stopLoss.ID
+
+	stopLossType := StopOrder
+	if bracket.StopLossLimitPrice > 0 {
+		stopLossType = StopLimitOrder
+		stopLoss.LimitPrice = bracket.StopLossLimitPrice
+	}
+	ob.PlaceOCOOrder(
+		OCOLeg{Type: LimitOrder, Price: bracket.TakeProfitPrice, Order: takeProfit},
+		OCOLeg{Type: stopLossType, Price: bracket.StopLossPrice, Order: stopLoss},
+	)
+}
@@ -0,0 +1,122 @@
+package entity_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBracketOrder(t *testing.T) {
+	Convey("When placing a bracket with a resting limit entry", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(105, entity.NewOrder(entity.ASK_ORDER, 10))
+
+		entry := entity.NewOrder(entity.BID_ORDER, 4)
+		entry.Owner = "alice"
+		_, err := ob.PlaceBracketOrder(entity.LimitOrder, 100, entry, entity.Bracket{
+			TakeProfitPrice: 110,
+			StopLossPrice:   95,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Should hold the exits back until the entry fills", func() {
+			So(entry.Bracket.TakeProfitOrderID, ShouldEqual, 0)
+			So(ob.StopOrders(), ShouldBeEmpty)
+
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 3))
+			So(entry.Bracket.TakeProfitOrderID, ShouldEqual, 0)
+		})
+
+		Convey("Should spring the exits as an OCO pair once the entry fills", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 4))
+
+			stops := ob.StopOrders()
+			So(stops, ShouldHaveLength, 1)
+			stopLoss := stops[0]
+			So(stopLoss.ID, ShouldEqual, entry.Bracket.StopLossOrderID)
+			So(stopLoss.OrderPlacement, ShouldEqual, entity.ASK_ORDER)
+			So(stopLoss.StopPrice, ShouldEqual, 95)
+			So(stopLoss.Size, ShouldEqual, 4)
+			So(stopLoss.Owner, ShouldEqual, "alice")
+			So(stopLoss.ParentOrderID, ShouldEqual, entry.ID)
+
+			So(ob.Asks()[1].Price, ShouldEqual, 110)
+			takeProfit := ob.Asks()[1].Orders.Front()
+			So(takeProfit.ID, ShouldEqual, entry.Bracket.TakeProfitOrderID)
+			So(takeProfit.LinkedOrderID, ShouldEqual, stopLoss.ID)
+
+			Convey("Should cancel the stop-loss when the take-profit fills", func() {
+				ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 14))
+
+				So(stopLoss.Status, ShouldEqual, entity.OrderStatusCancelled)
+				So(stopLoss.CancelReason, ShouldEqual, entity.CancelReasonOCOSibling)
+			})
+		})
+
+		Convey("Should spring the exits for the filled part when the entry is cancelled", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 1))
+			So(ob.Cancel(entry.ID), ShouldBeNil)
+
+			So(ob.StopOrders(), ShouldHaveLength, 1)
+			So(ob.StopOrders()[0].Size, ShouldEqual, 1)
+		})
+
+		Convey("Should spring nothing when the entry is cancelled unfilled", func() {
+			So(ob.Cancel(entry.ID), ShouldBeNil)
+
+			So(ob.StopOrders(), ShouldBeEmpty)
+			So(ob.AskTotalVolume(), ShouldEqual, 10)
+		})
+
+		Convey("Should keep the bracket across snapshots", func() {
+			var buf bytes.Buffer
+			So(entity.EncodeSnapshot(&buf, ob.Snapshot()), ShouldBeNil)
+			snapshot, err := entity.DecodeSnapshot(&buf)
+			So(err, ShouldBeNil)
+
+			restored := entity.RestoreOrderBook(snapshot, entity.DefaultMarketConfig)
+			restored.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 4))
+
+			So(restored.StopOrders(), ShouldHaveLength, 1)
+			So(restored.StopOrders()[0].ParentOrderID, ShouldEqual, entry.ID)
+		})
+	})
+
+	Convey("When placing a bracket with a market entry", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.BID_ORDER, 10))
+
+		entry := entity.NewOrder(entity.ASK_ORDER, 2)
+		_, err := ob.PlaceBracketOrder(entity.MarketOrder, 0, entry, entity.Bracket{
+			TakeProfitPrice:    90,
+			StopLossPrice:      105,
+			StopLossLimitPrice: 106,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Should spring the exits right away", func() {
+			stops := ob.StopOrders()
+			So(stops, ShouldHaveLength, 1)
+			So(stops[0].OrderPlacement, ShouldEqual, entity.BID_ORDER)
+			So(stops[0].LimitPrice, ShouldEqual, 106)
+			So(ob.Bids()[0].Price, ShouldEqual, 100)
+		})
+	})
+
+	Convey("When the take-profit is on the wrong side of the stop-loss", t, func() {
+		ob := entity.NewOrderBook("test")
+		entry := entity.NewOrder(entity.BID_ORDER, 2)
+		_, err := ob.PlaceBracketOrder(entity.LimitOrder, 100, entry, entity.Bracket{
+			TakeProfitPrice: 95,
+			StopLossPrice:   110,
+		})
+
+		Convey("Should reject the entry", func() {
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(entry.Status, ShouldEqual, entity.OrderStatusRejected)
+		})
+	})
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"hash/crc32"
+	"strconv"
+)
+
+// ChecksumDepth is how many levels per side the book checksum covers.
+const ChecksumDepth = 10
+
+// Checksum is the CRC32 (IEEE) of the top ChecksumDepth levels of each side, for clients keeping a local
+// copy of the book to tell whether it drifted. As with Kraken, it's computed over a string concatenating
+// the price then the size of each ask level, best first, followed by each bid level, best first. Prices
+// are written as their number of ticks and sizes as their number of SizeDecimals units, which is the
+// fixed-precision decimal with the point and leading zeros removed.
+func (ob *OrderBook) Checksum() uint32 {
+	buf := make([]byte, 0, 2*ChecksumDepth*32)
+	for _, side := range []*priceLevels{ob.asks, ob.bids} {
+		depth := 0
+		side.walk(func(ticks int64, level *Limit) bool {
+			buf = strconv.AppendInt(buf, ticks, 10)
+			buf = strconv.AppendInt(buf, toSizeUnits(level.TotalVolume), 10)
+			depth++
+			return depth < ChecksumDepth
+		})
+	}
+	return crc32.ChecksumIEEE(buf)
+}
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// MarketState is the trading state of a book.
+type MarketState string
+
+const (
+	MarketStateOpen MarketState = "OPEN"
+	// MarketStateHalted rejects new orders until the market's halt cooldown passes. Cancels still go through.
+	MarketStateHalted MarketState = "HALTED"
+	// MarketStateAuction collects orders without matching them until the auction is uncrossed
+	MarketStateAuction MarketState = "AUCTION"
+)
+
+// State returns the trading state of the book. A halted market resumes once its cooldown passes.
+func (ob *OrderBook) State() MarketState {
+	switch {
+	case ob.auction:
+		return MarketStateAuction
+	case ob.isHalted():
+		return MarketStateHalted
+	}
+	return MarketStateOpen
+}
+
+// HaltedUntil returns when a halted market resumes trading, or false if it isn't halted.
+func (ob *OrderBook) HaltedUntil() (time.Time, bool) {
+	if !ob.isHalted() {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ob.haltedUntil), true
+}
+
+// PriceBand returns the lowest and highest prices orders may trade at, PriceBandPct either side of the last
+// trade price, or false if the book has no band: it isn't configured, nothing traded yet or an auction is
+// finding a new price.
+func (ob *OrderBook) PriceBand() (low, high float64, ok bool) {
+	if ob.Config.PriceBandPct <= 0 || ob.lastTradePrice <= 0 || ob.auction {
+		return 0, 0, false
+	}
+	low = ob.Config.RoundToTick(ob.lastTradePrice * (1 - ob.Config.PriceBandPct/100))
+	high = ob.Config.RoundToTick(ob.lastTradePrice * (1 + ob.Config.PriceBandPct/100))
+	return low, high, true
+}
+
+// checkTradingState returns a RejectError while the market is halted, or when price is outside the price
+// band. A zero price, as for market orders, is only checked against the halt.
+func (ob *OrderBook) checkTradingState(price float64) error {
+	if until, halted := ob.HaltedUntil(); halted {
+		return &RejectError{Reason: RejectReasonMarketHalted, msg: fmt.Sprintf("%s is halted until %s", ob.Market, until.Format(time.RFC3339))}
+	}
+
+	low, high, ok := ob.PriceBand()
+	if ok && price != 0 && (ob.Config.ToTicks(price) < ob.Config.ToTicks(low) || ob.Config.ToTicks(price) > ob.Config.ToTicks(high)) {
+		return &RejectError{Reason: RejectReasonPriceOutOfBand, msg: fmt.Sprintf("price %.2f is outside the %s price band %.2f - %.2f", price, ob.Market, low, high)}
+	}
+	return nil
+}
+
+// bandPrice tightens the worst price a market order may fill at to the edge of the price band, reporting
+// whether the band is the tighter limit. A zero limitPrice is unbounded.
+func (ob *OrderBook) bandPrice(placement OrderPlacement, limitPrice float64) (price float64, banded bool) {
+	low, high, ok := ob.PriceBand()
+	if !ok {
+		return limitPrice, false
+	}
+
+	edge := low
+	if placement == BID_ORDER {
+		edge = high
+	}
+	if limitPrice == 0 || !ob.crosses(placement, edge, limitPrice) {
+		return edge, true
+	}
+	return limitPrice, false
+}
+
+// halt stops trading for the market's HaltCooldown.
+func (ob *OrderBook) halt() {
+	ob.haltedUntil = ob.now().Add(ob.Config.HaltCooldown).UnixNano()
+}
+
+// isHalted reports whether the market is halted and its cooldown hasn't passed yet.
+func (ob *OrderBook) isHalted() bool {
+	return ob.haltedUntil != 0 && ob.now().UnixNano() < ob.haltedUntil
+}
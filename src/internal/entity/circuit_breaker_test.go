@@ -0,0 +1,78 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("When a market has a price band", t, func() {
+		ob := entity.NewOrderBookWithConfig("test", entity.MarketConfig{TickSize: 0.01, PriceBandPct: 10, HaltCooldown: 50 * time.Millisecond})
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 1))
+		ob.PlaceLimitOrder(105, entity.NewOrder(entity.ASK_ORDER, 1))
+		outside := entity.NewOrder(entity.ASK_ORDER, 1)
+		ob.PlaceLimitOrder(120, outside)
+
+		Convey("Should have no band before the first trade", func() {
+			_, _, ok := ob.PriceBand()
+			So(ok, ShouldBeFalse)
+		})
+
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+		bid := entity.NewOrder(entity.BID_ORDER, 1)
+		ob.PlaceLimitOrder(95, bid)
+
+		Convey("Should band prices around the last trade", func() {
+			low, high, ok := ob.PriceBand()
+			So(ok, ShouldBeTrue)
+			So(low, ShouldEqual, 90)
+			So(high, ShouldEqual, 110)
+			So(ob.State(), ShouldEqual, entity.MarketStateOpen)
+		})
+
+		Convey("Should reject limit orders priced outside the band", func() {
+			_, err := ob.PlaceLimitOrder(111, entity.NewOrder(entity.ASK_ORDER, 1))
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonPriceOutOfBand)
+		})
+
+		Convey("Should reject amends outside the band and keep the order", func() {
+			_, err := ob.ReplaceOrder(bid.ID, 80, 0)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonPriceOutOfBand)
+			So(bid.Limit.Price, ShouldEqual, 95)
+		})
+
+		Convey("When a market order reaches past the band", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 2)
+			matches, err := ob.PlaceMarketOrder(order)
+
+			Convey("Should fill within the band and cancel the remainder", func() {
+				So(err, ShouldBeNil)
+				So(matches, ShouldHaveLength, 1)
+				So(matches[0].Price, ShouldEqual, 105)
+				So(order.Status, ShouldEqual, entity.OrderStatusCancelled)
+				So(order.CancelReason, ShouldEqual, entity.CancelReasonCircuitBreaker)
+				So(outside.Status, ShouldEqual, entity.OrderStatusNew)
+			})
+
+			Convey("Should halt the market, still allowing cancels", func() {
+				So(ob.State(), ShouldEqual, entity.MarketStateHalted)
+				_, err := ob.PlaceLimitOrder(104, entity.NewOrder(entity.ASK_ORDER, 1))
+				reason, _ := entity.RejectReasonOf(err)
+				So(reason, ShouldEqual, entity.RejectReasonMarketHalted)
+				So(ob.Cancel(bid.ID), ShouldBeNil)
+			})
+
+			Convey("Should resume once the cooldown passes", func() {
+				time.Sleep(60 * time.Millisecond)
+				So(ob.State(), ShouldEqual, entity.MarketStateOpen)
+				_, err := ob.PlaceLimitOrder(104, entity.NewOrder(entity.ASK_ORDER, 1))
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
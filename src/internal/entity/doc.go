@@ -0,0 +1,5 @@
+// Package entity implements the in-memory limit order book and its matching rules.
+//
+// It only depends on the standard library. Programs outside this module should import it through
+// github.com/idzharbae/crypto-exchange/src/pkg/orderbook, which re-exports the stable API.
+package entity
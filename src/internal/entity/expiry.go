@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ExpireOrders cancels every order of this book whose ExpiresAt is at or before now with CancelReasonExpired,
+// which moves it to EXPIRED and notifies OnTransition. It returns the expired orders, earliest expiry first.
+func (ob *OrderBook) ExpireOrders(now time.Time) Orders {
+	expired := Orders{}
+	for ob.expiries.Len() > 0 && ob.expiries[0].ExpiresAt <= now.UnixNano() {
+		order := heap.Pop(&ob.expiries).(*Order)
+		if err := ob.CancelOrderByID(order.ID, order.OrderPlacement, CancelReasonExpired); err != nil {
+			continue
+		}
+		expired = append(expired, order)
+	}
+
+	return expired
+}
+
+// NextExpiry returns when the earliest good-till-date order of this book expires, if there's any.
+func (ob *OrderBook) NextExpiry() (time.Time, bool) {
+	if ob.expiries.Len() == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ob.expiries[0].ExpiresAt), true
+}
+
+// queueExpiry queues a resting good-till-date order for expiry. An order already queued, such as a triggered
+// stop order resting as a limit order, stays queued once.
+func (ob *OrderBook) queueExpiry(order *Order) {
+	if order.ExpiresAt != 0 && order.expiryIndex == 0 {
+		heap.Push(&ob.expiries, order)
+	}
+}
+
+// unqueueExpiry takes an order that left the book out of the expiry queue.
+func (ob *OrderBook) unqueueExpiry(order *Order) {
+	if order.expiryIndex != 0 {
+		heap.Remove(&ob.expiries, order.expiryIndex-1)
+	}
+}
+
+// expiryQueue is a min-heap of the good-till-date orders resting in a book, by ExpiresAt. Each order keeps
+// its position so it can be taken out once it leaves the book.
+type expiryQueue Orders
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].ExpiresAt < q[j].ExpiresAt }
+
+func (q expiryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].expiryIndex, q[j].expiryIndex = i+1, j+1
+}
+
+func (q *expiryQueue) Push(x any) {
+	order := x.(*Order)
+	*q = append(*q, order)
+	order.expiryIndex = len(*q)
+}
+
+func (q *expiryQueue) Pop() any {
+	old := *q
+	order := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	order.expiryIndex = 0
+	return order
+}
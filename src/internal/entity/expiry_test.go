@@ -0,0 +1,54 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpiryQueue(t *testing.T) {
+	Convey("When good-till-date orders come and go", t, func() {
+		now := time.Unix(1_000_000, 0)
+		ob := entity.NewOrderBook("test")
+		gtd := func(placement entity.OrderPlacement, size float64, expiresIn time.Duration) *entity.Order {
+			order := entity.NewOrder(placement, size)
+			order.ExpiresAt = now.Add(expiresIn).UnixNano()
+			return order
+		}
+
+		Convey("Should only queue the ones that rest", func() {
+			ob.PlaceLimitOrder(-1, gtd(entity.BID_ORDER, 1, time.Minute))
+			ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 1))
+			ob.PlaceLimitOrder(100, gtd(entity.BID_ORDER, 1, time.Minute))
+
+			_, queued := ob.NextExpiry()
+			So(queued, ShouldBeFalse)
+		})
+
+		Convey("Should unqueue the ones that leave the book", func() {
+			soon := gtd(entity.BID_ORDER, 1, time.Minute)
+			ob.PlaceLimitOrder(99, soon)
+			ob.PlaceLimitOrder(98, gtd(entity.BID_ORDER, 1, time.Hour))
+			So(ob.Cancel(soon.ID), ShouldBeNil)
+
+			next, queued := ob.NextExpiry()
+			So(queued, ShouldBeTrue)
+			So(next, ShouldEqual, now.Add(time.Hour))
+		})
+
+		Convey("Should expire a triggered stop order resting as a limit order once", func() {
+			ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 1))
+			stop := gtd(entity.BID_ORDER, 1, time.Minute)
+			So(ob.PlaceStopLimitOrder(100, 99, stop), ShouldBeNil)
+			ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+			So(stop.Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.StopOrders(), ShouldBeEmpty)
+
+			So(ob.ExpireOrders(now.Add(time.Minute)), ShouldResemble, entity.Orders{stop})
+			_, queued := ob.NextExpiry()
+			So(queued, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+)
+
+// L2SnapshotVersion is bumped whenever the L2 snapshot or delta format changes incompatibly.
+const L2SnapshotVersion = 1
+
+// L2Snapshot is the aggregated size at every price level of a book, best levels first, as of Sequence.
+// Applying the LevelDeltas with a higher sequence keeps it up to date.
+type L2Snapshot struct {
+	Version  int       `json:"version"`
+	Market   string    `json:"market"`
+	Sequence int64     `json:"sequence"`
+	Bids     []L2Level `json:"bids"`
+	Asks     []L2Level `json:"asks"`
+	// Checksum is the book's Checksum as of Sequence, whatever levels the snapshot holds
+	Checksum uint32 `json:"checksum"`
+}
+
+type L2Level struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+type LevelAction string
+
+const (
+	LevelAdded   LevelAction = "ADDED"
+	LevelChanged LevelAction = "CHANGED"
+	LevelRemoved LevelAction = "REMOVED"
+)
+
+// LevelDelta is the new total size of a price level, or its removal with a zero size. Sequence is the
+// book's mutation sequence once the operation that changed the level completed, so every delta of one
+// operation shares it, as does Checksum, the book's Checksum once every delta of the operation is applied.
+type LevelDelta struct {
+	Sequence  int64          `json:"sequence"`
+	Placement OrderPlacement `json:"placement"`
+	Price     float64        `json:"price"`
+	Size      float64        `json:"size"`
+	Action    LevelAction    `json:"action"`
+	Checksum  uint32         `json:"checksum"`
+}
+
+// levelKey identifies a price level across its removal and re-creation.
+type levelKey struct {
+	placement OrderPlacement
+	ticks     int64
+}
+
+// L2Snapshot returns the current size of every price level.
+func (ob *OrderBook) L2Snapshot() L2Snapshot {
+	return L2Snapshot{
+		Version:  L2SnapshotVersion,
+		Market:   ob.Market,
+		Sequence: ob.mutationSequence,
+		Bids:     l2Levels(ob.bids.all()),
+		Asks:     l2Levels(ob.asks.all()),
+		Checksum: ob.Checksum(),
+	}
+}
+
+// Depth returns up to limit levels per side, aggregated into buckets of the given size: a multiple of the
+// tick size, or zero for one bucket per tick. Bids are bucketed down and asks up, so a bucket never shows
+// a better price than the orders it holds.
+func (ob *OrderBook) Depth(limit int, aggregation float64) (L2Snapshot, error) {
+	if limit <= 0 {
+		return L2Snapshot{}, fmt.Errorf("Depth: invalid limit %d", limit)
+	}
+	bucketTicks := int64(1)
+	if aggregation != 0 {
+		bucketTicks = ob.Config.ToTicks(aggregation)
+		if bucketTicks <= 0 || ob.Config.FromTicks(bucketTicks) != aggregation {
+			return L2Snapshot{}, fmt.Errorf("Depth: aggregation %v isn't a multiple of the tick size %v", aggregation, ob.Config.TickSize)
+		}
+	}
+
+	return L2Snapshot{
+		Version:  L2SnapshotVersion,
+		Market:   ob.Market,
+		Sequence: ob.mutationSequence,
+		Bids:     ob.depthLevels(ob.bids, bucketTicks, limit),
+		Asks:     ob.depthLevels(ob.asks, bucketTicks, limit),
+		Checksum: ob.Checksum(),
+	}, nil
+}
+
+func (ob *OrderBook) depthLevels(side *priceLevels, bucketTicks int64, limit int) []L2Level {
+	levels := []L2Level{}
+	var lastBucket int64
+	side.walk(func(ticks int64, level *Limit) bool {
+		bucket := ticks / bucketTicks * bucketTicks
+		if !side.descending && bucket != ticks {
+			bucket += bucketTicks
+		}
+
+		if len(levels) > 0 && bucket == lastBucket {
+			levels[len(levels)-1].Size = addSize(levels[len(levels)-1].Size, level.TotalVolume)
+			return true
+		}
+		if len(levels) == limit {
+			return false
+		}
+		levels = append(levels, L2Level{Price: ob.Config.FromTicks(bucket), Size: level.TotalVolume})
+		lastBucket = bucket
+		return true
+	})
+	return levels
+}
+
+func l2Levels(limits []*Limit) []L2Level {
+	levels := make([]L2Level, 0, len(limits))
+	for _, limit := range limits {
+		levels = append(levels, L2Level{Price: limit.Price, Size: limit.TotalVolume})
+	}
+	return levels
+}
+
+// trackLevel records changes to a new level's size until it's removed.
+func (ob *OrderBook) trackLevel(placement OrderPlacement, ticks int64, limit *Limit) {
+	key := levelKey{placement, ticks}
+	ob.markLevel(key, false)
+	limit.onChange = func() {
+		ob.markLevel(key, true)
+	}
+}
+
+// markLevel records that the level changed during the current operation, and whether it existed before.
+// Only the first change of an operation tells that.
+func (ob *OrderBook) markLevel(key levelKey, existed bool) {
+	if ob.OnLevelChange == nil {
+		return
+	}
+	if ob.changedLevels == nil {
+		ob.changedLevels = make(map[levelKey]bool)
+	}
+	if _, marked := ob.changedLevels[key]; !marked {
+		ob.changedLevels[key] = existed
+	}
+}
+
+// notifyLevels calls OnLevelChange once for every level the completed operation changed, bids then asks,
+// by price. Levels added and removed again within the operation are left out.
+func (ob *OrderBook) notifyLevels() {
+	if len(ob.changedLevels) == 0 {
+		return
+	}
+
+	keys := make([]levelKey, 0, len(ob.changedLevels))
+	for key := range ob.changedLevels {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].placement != keys[j].placement {
+			return keys[i].placement == BID_ORDER
+		}
+		return keys[i].ticks < keys[j].ticks
+	})
+
+	checksum := ob.Checksum()
+
+	for _, key := range keys {
+		existed := ob.changedLevels[key]
+		levels := ob.AskLimits
+		if key.placement == BID_ORDER {
+			levels = ob.BidLimits
+		}
+
+		delta := LevelDelta{
+			Sequence:  ob.mutationSequence,
+			Placement: key.placement,
+			Price:     ob.Config.FromTicks(key.ticks),
+			Checksum:  checksum,
+		}
+		limit, exists := levels[key.ticks]
+		switch {
+		case exists && existed:
+			delta.Action, delta.Size = LevelChanged, limit.TotalVolume
+		case exists:
+			delta.Action, delta.Size = LevelAdded, limit.TotalVolume
+		case existed:
+			delta.Action = LevelRemoved
+		default:
+			continue
+		}
+		ob.OnLevelChange(delta)
+	}
+	clear(ob.changedLevels)
+}
@@ -0,0 +1,236 @@
+package entity_test
+
+import (
+	"hash/crc32"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLevelDeltas(t *testing.T) {
+	Convey("When price levels change", t, func() {
+		ob := entity.NewOrderBook("test")
+		deltas := []entity.LevelDelta{}
+		ob.OnLevelChange = func(delta entity.LevelDelta) {
+			// The checksum is covered by TestChecksum
+			So(delta.Checksum, ShouldEqual, ob.Checksum())
+			delta.Checksum = 0
+			deltas = append(deltas, delta)
+		}
+
+		resting := entity.NewOrder(entity.ASK_ORDER, 2)
+		ob.PlaceLimitOrder(101, resting)
+		ob.PlaceLimitOrder(102, entity.NewOrder(entity.ASK_ORDER, 3))
+
+		Convey("Should emit added levels with the sequence of the operation", func() {
+			So(deltas, ShouldResemble, []entity.LevelDelta{
+				{Sequence: 1, Placement: entity.ASK_ORDER, Price: 101, Size: 2, Action: entity.LevelAdded},
+				{Sequence: 2, Placement: entity.ASK_ORDER, Price: 102, Size: 3, Action: entity.LevelAdded},
+			})
+		})
+
+		Convey("Should emit one delta per level once a sweep completes", func() {
+			deltas = deltas[:0]
+			ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 3))
+
+			sequence := ob.MutationSequence()
+			So(deltas, ShouldResemble, []entity.LevelDelta{
+				{Sequence: sequence, Placement: entity.ASK_ORDER, Price: 101, Action: entity.LevelRemoved},
+				{Sequence: sequence, Placement: entity.ASK_ORDER, Price: 102, Size: 2, Action: entity.LevelChanged},
+			})
+		})
+
+		Convey("Should not add a level for a limit order that fills completely", func() {
+			deltas = deltas[:0]
+			ob.PlaceLimitOrder(103, entity.NewOrder(entity.BID_ORDER, 5))
+
+			So(deltas, ShouldResemble, []entity.LevelDelta{
+				{Sequence: ob.MutationSequence(), Placement: entity.ASK_ORDER, Price: 101, Action: entity.LevelRemoved},
+				{Sequence: ob.MutationSequence(), Placement: entity.ASK_ORDER, Price: 102, Action: entity.LevelRemoved},
+			})
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Should emit size reductions of an amended order", func() {
+			deltas = deltas[:0]
+			ob.ReplaceOrder(resting.ID, 0, 1)
+
+			So(deltas, ShouldResemble, []entity.LevelDelta{
+				{Sequence: ob.MutationSequence(), Placement: entity.ASK_ORDER, Price: 101, Size: 1, Action: entity.LevelChanged},
+			})
+		})
+	})
+
+	Convey("When applying the deltas after a snapshot to it", t, func() {
+		ob := entity.NewOrderBook("test")
+		random := rand.New(rand.NewSource(1))
+		place := func() {
+			placement := entity.BID_ORDER
+			if random.Intn(2) == 0 {
+				placement = entity.ASK_ORDER
+			}
+			order := entity.NewOrder(placement, float64(1+random.Intn(5)))
+			order.AllowPartialFill = true
+			if random.Intn(5) == 0 {
+				ob.PlaceMarketOrder(order)
+			} else {
+				ob.PlaceLimitOrder(float64(95+random.Intn(10)), order)
+			}
+		}
+		for i := 0; i < 50; i++ {
+			place()
+		}
+
+		snapshot := ob.L2Snapshot()
+		replica := map[entity.OrderPlacement]map[float64]float64{entity.BID_ORDER: {}, entity.ASK_ORDER: {}}
+		for _, level := range snapshot.Bids {
+			replica[entity.BID_ORDER][level.Price] = level.Size
+		}
+		for _, level := range snapshot.Asks {
+			replica[entity.ASK_ORDER][level.Price] = level.Size
+		}
+
+		ob.OnLevelChange = func(delta entity.LevelDelta) {
+			So(delta.Sequence, ShouldBeGreaterThan, snapshot.Sequence)
+			if delta.Action == entity.LevelRemoved {
+				delete(replica[delta.Placement], delta.Price)
+			} else {
+				replica[delta.Placement][delta.Price] = delta.Size
+			}
+		}
+		for i := 0; i < 200; i++ {
+			place()
+		}
+
+		Convey("Should rebuild the current levels", func() {
+			current := ob.L2Snapshot()
+			So(replica[entity.BID_ORDER], ShouldHaveLength, len(current.Bids))
+			for _, level := range current.Bids {
+				So(replica[entity.BID_ORDER][level.Price], ShouldEqual, level.Size)
+			}
+			So(replica[entity.ASK_ORDER], ShouldHaveLength, len(current.Asks))
+			for _, level := range current.Asks {
+				So(replica[entity.ASK_ORDER][level.Price], ShouldEqual, level.Size)
+			}
+		})
+	})
+}
+
+func TestChecksum(t *testing.T) {
+	Convey("When checksumming a book", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100.5, entity.NewOrder(entity.ASK_ORDER, 1.25))
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 3))
+		ob.PlaceLimitOrder(99, entity.NewOrder(entity.BID_ORDER, 0.5))
+
+		Convey("Should hash asks then bids as ticks and size units, best first", func() {
+			expected := crc32.ChecksumIEEE([]byte("10050" + "125000000" + "10100" + "300000000" + "9900" + "50000000"))
+			So(ob.Checksum(), ShouldEqual, expected)
+			So(ob.L2Snapshot().Checksum, ShouldEqual, expected)
+		})
+
+		Convey("Should only cover the top levels", func() {
+			checksum := ob.Checksum()
+			for i := 0; i < entity.ChecksumDepth; i++ {
+				ob.PlaceLimitOrder(float64(90+i), entity.NewOrder(entity.ASK_ORDER, 1))
+			}
+			So(ob.Checksum(), ShouldNotEqual, checksum)
+
+			checksum = ob.Checksum()
+			ob.PlaceLimitOrder(200, entity.NewOrder(entity.ASK_ORDER, 1))
+			So(ob.Checksum(), ShouldEqual, checksum)
+		})
+
+		Convey("Should stamp deltas with the checksum after the whole operation", func() {
+			deltas := []entity.LevelDelta{}
+			ob.OnLevelChange = func(delta entity.LevelDelta) {
+				deltas = append(deltas, delta)
+			}
+			buy := entity.NewOrder(entity.BID_ORDER, 2)
+			ob.PlaceLimitOrder(101, buy)
+
+			So(deltas, ShouldHaveLength, 2)
+			for _, delta := range deltas {
+				So(delta.Checksum, ShouldEqual, ob.Checksum())
+			}
+		})
+	})
+}
+
+func TestDepth(t *testing.T) {
+	Convey("When reading the depth of a book", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(99.99, entity.NewOrder(entity.BID_ORDER, 1))
+		ob.PlaceLimitOrder(99.5, entity.NewOrder(entity.BID_ORDER, 2))
+		ob.PlaceLimitOrder(99.49, entity.NewOrder(entity.BID_ORDER, 4))
+		ob.PlaceLimitOrder(100.01, entity.NewOrder(entity.ASK_ORDER, 1))
+		ob.PlaceLimitOrder(100.5, entity.NewOrder(entity.ASK_ORDER, 2))
+		ob.PlaceLimitOrder(100.51, entity.NewOrder(entity.ASK_ORDER, 4))
+
+		Convey("Should list every level without aggregation", func() {
+			depth, err := ob.Depth(2, 0)
+			So(err, ShouldBeNil)
+			So(depth.Sequence, ShouldEqual, ob.MutationSequence())
+			So(depth.Bids, ShouldResemble, []entity.L2Level{{Price: 99.99, Size: 1}, {Price: 99.5, Size: 2}})
+			So(depth.Asks, ShouldResemble, []entity.L2Level{{Price: 100.01, Size: 1}, {Price: 100.5, Size: 2}})
+		})
+
+		Convey("Should bucket bids down and asks up", func() {
+			depth, err := ob.Depth(50, 0.5)
+			So(err, ShouldBeNil)
+			So(depth.Bids, ShouldResemble, []entity.L2Level{{Price: 99.5, Size: 3}, {Price: 99, Size: 4}})
+			So(depth.Asks, ShouldResemble, []entity.L2Level{{Price: 100.5, Size: 3}, {Price: 101, Size: 4}})
+		})
+
+		Convey("Should cap the number of buckets", func() {
+			depth, _ := ob.Depth(1, 0.5)
+			So(depth.Bids, ShouldResemble, []entity.L2Level{{Price: 99.5, Size: 3}})
+			So(depth.Asks, ShouldResemble, []entity.L2Level{{Price: 100.5, Size: 3}})
+		})
+
+		Convey("Should refuse aggregations that aren't a multiple of the tick size", func() {
+			_, err := ob.Depth(50, 0.005)
+			So(err, ShouldNotBeNil)
+			_, err = ob.Depth(50, -1)
+			So(err, ShouldNotBeNil)
+			_, err = ob.Depth(0, 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestL3Snapshot(t *testing.T) {
+	Convey("When reading every order of a book", t, func() {
+		ob := entity.NewOrderBook("test")
+		first := entity.NewOrder(entity.BID_ORDER, 1)
+		first.Owner = "alice"
+		second := entity.NewOrder(entity.BID_ORDER, 2)
+		second.Owner = "bob"
+		ob.PlaceLimitOrder(99, first)
+		ob.PlaceLimitOrder(99, second)
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 3))
+
+		snapshot := ob.L3Snapshot()
+
+		Convey("Should list the orders of each level in queue order", func() {
+			So(snapshot.Sequence, ShouldEqual, ob.MutationSequence())
+			So(snapshot.Bids, ShouldHaveLength, 1)
+			So(snapshot.Bids[0].Size, ShouldEqual, 3)
+			So(snapshot.Bids[0].Orders, ShouldResemble, []entity.L3Order{
+				{ID: first.ID, Size: 1, QueuePosition: 0, Timestamp: time.Unix(0, first.Timestamp).UnixMilli()},
+				{ID: second.ID, Size: 2, QueuePosition: 1, Timestamp: time.Unix(0, second.Timestamp).UnixMilli()},
+			})
+			So(snapshot.Asks[0].Orders, ShouldHaveLength, 1)
+		})
+
+		Convey("Should move orders up the queue as the ones ahead leave", func() {
+			So(second.Limit.QueuePosition(second), ShouldEqual, 1)
+			ob.CancelOrderByID(first.ID, entity.BID_ORDER, entity.CancelReasonUserRequested)
+			So(second.Limit.QueuePosition(second), ShouldEqual, 0)
+			So(ob.L3Snapshot().Bids[0].Orders[0].QueuePosition, ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+// L3SnapshotVersion is bumped whenever the L3 snapshot format changes incompatibly.
+const L3SnapshotVersion = 1
+
+// L3Snapshot is every order resting in a book, level by level, best levels first, as of Sequence. It
+// leaves out who owns the orders.
+type L3Snapshot struct {
+	Version  int       `json:"version"`
+	Market   string    `json:"market"`
+	Sequence int64     `json:"sequence"`
+	Bids     []L3Level `json:"bids"`
+	Asks     []L3Level `json:"asks"`
+}
+
+type L3Level struct {
+	Price  float64   `json:"price"`
+	Size   float64   `json:"size"`
+	Orders []L3Order `json:"orders"`
+}
+
+// L3Order is a resting order without its owner. QueuePosition is the number of orders ahead of it at its
+// level, so the order at position 0 fills first. Timestamp is in Unix milliseconds, as the API's timestamps.
+type L3Order struct {
+	ID            int64   `json:"id"`
+	Size          float64 `json:"size"`
+	QueuePosition int     `json:"queue_position"`
+	Timestamp     int64   `json:"timestamp"`
+	// TimestampISO is left for the API to fill in when it's asked for ISO-8601 timestamps
+	TimestampISO string `json:"timestamp_iso,omitempty"`
+}
+
+// L3Snapshot returns every resting order with its queue position.
+func (ob *OrderBook) L3Snapshot() L3Snapshot {
+	return L3Snapshot{
+		Version:  L3SnapshotVersion,
+		Market:   ob.Market,
+		Sequence: ob.mutationSequence,
+		Bids:     l3Levels(ob.bids.all()),
+		Asks:     l3Levels(ob.asks.all()),
+	}
+}
+
+func l3Levels(limits []*Limit) []L3Level {
+	levels := make([]L3Level, 0, len(limits))
+	for _, limit := range limits {
+		orders := make([]L3Order, 0, limit.Orders.Len())
+		for position, order := range limit.Orders.All() {
+			orders = append(orders, L3Order{
+				ID:            order.ID,
+				Size:          order.Size,
+				QueuePosition: position,
+				Timestamp:     time.Unix(0, order.Timestamp).UnixMilli(),
+			})
+		}
+		levels = append(levels, L3Level{Price: limit.Price, Size: limit.TotalVolume, Orders: orders})
+	}
+	return levels
+}
+
+// QueuePosition returns the number of orders ahead of o at its level, or -1 if it isn't resting.
+func (l *Limit) QueuePosition(o *Order) int {
+	if o.Limit != l || o.node == nil {
+		return -1
+	}
+	position := 0
+	for e := o.node.Prev(); e != nil; e = e.Prev() {
+		position++
+	}
+	return position
+}
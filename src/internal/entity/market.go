@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarketConfig holds the per-market trading parameters of an order book.
+type MarketConfig struct {
+	// TickSize is the minimum price increment. Prices are kept as integer multiples of it internally.
+	TickSize float64
+	// MaxSweepDepth caps how many price levels a single market order may consume; the remainder is cancelled.
+	// Zero means unlimited.
+	MaxSweepDepth int
+	// PriceBandPct is how far from the last trade price, in percent, orders may trade. Limit orders priced
+	// outside the band are rejected, and a market order reaching past it has its remainder cancelled and
+	// halts the market for HaltCooldown. Zero disables the band.
+	PriceBandPct float64
+	HaltCooldown time.Duration
+	// LotSize is the minimum size increment; order sizes must be whole multiples of it. Zero accepts any size
+	// down to SizeDecimals.
+	LotSize float64
+	// MinNotional is the smallest price times size a limit order may have. Zero disables it.
+	MinNotional float64
+}
+
+var DefaultMarketConfig = MarketConfig{
+	TickSize: 0.01,
+}
+
+// ToTicks converts a decimal price to the nearest whole number of ticks.
+func (c MarketConfig) ToTicks(price float64) int64 {
+	return int64(math.Round(price / c.TickSize))
+}
+
+// FromTicks converts a tick count back to a decimal price, rounded to the tick size precision.
+func (c MarketConfig) FromTicks(ticks int64) float64 {
+	scale := math.Pow10(c.tickDecimals())
+	return math.Round(float64(ticks)*c.TickSize*scale) / scale
+}
+
+// RoundToTick rounds a price to the nearest tick, the price its order will actually rest at.
+func (c MarketConfig) RoundToTick(price float64) float64 {
+	return c.FromTicks(c.ToTicks(price))
+}
+
+// RoundToLot rounds a size down to a whole number of lots.
+func (c MarketConfig) RoundToLot(size float64) float64 {
+	if c.LotSize == 0 {
+		return RoundSize(size)
+	}
+	lots := math.Floor(size/c.LotSize + incrementTolerance)
+	return RoundSize(lots * c.LotSize)
+}
+
+// incrementTolerance is how far from a whole number of ticks or lots, in ticks or lots, a price or size may
+// be and still count as on one, so binary floating point noise such as 0.1+0.2 isn't rejected.
+const incrementTolerance = 1e-6
+
+// CheckOrder returns a RejectError if the size isn't positive, the price is negative or isn't on a tick,
+// the size isn't a whole number of lots or the order is worth less than MinNotional. A zero price only
+// checks the size, as for market orders.
+func (c MarketConfig) CheckOrder(price, size float64) error {
+	if size <= 0 {
+		return &RejectError{Reason: RejectReasonSizeTooSmall, msg: fmt.Sprintf("size %v isn't positive", size)}
+	}
+	if price < 0 {
+		return &RejectError{Reason: RejectReasonInvalidOrder, msg: fmt.Sprintf("price %v is negative", price)}
+	}
+	if price != 0 && !isMultiple(price, c.TickSize) {
+		return &RejectError{Reason: RejectReasonInvalidTickSize, msg: fmt.Sprintf("price %v isn't a multiple of the tick size %v", price, c.TickSize)}
+	}
+	if c.LotSize != 0 && !isMultiple(size, c.LotSize) {
+		return &RejectError{Reason: RejectReasonInvalidLotSize, msg: fmt.Sprintf("size %v isn't a multiple of the lot size %v", size, c.LotSize)}
+	}
+	if price != 0 && price*size < c.MinNotional {
+		return &RejectError{Reason: RejectReasonBelowMinNotional, msg: fmt.Sprintf("notional %v is below the minimum of %v", price*size, c.MinNotional)}
+	}
+	return nil
+}
+
+func isMultiple(value, increment float64) bool {
+	n := value / increment
+	return math.Abs(n-math.Round(n)) < incrementTolerance
+}
+
+func (c MarketConfig) tickDecimals() int {
+	tick := strconv.FormatFloat(c.TickSize, 'f', -1, 64)
+	if dot := strings.IndexByte(tick, '.'); dot >= 0 {
+		return len(tick) - dot - 1
+	}
+	return 0
+}
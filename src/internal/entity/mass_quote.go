@@ -0,0 +1,57 @@
+package entity
+
+import "fmt"
+
+// Quote is one price level of a maker's mass quote.
+type Quote struct {
+	Placement OrderPlacement `json:"placement"`
+	Price     float64        `json:"price"`
+	Size      float64        `json:"size"`
+}
+
+// MassQuote replaces the owner's previous quote set in this book: the still open quotes of the
+// previous set are cancelled and the new quotes are placed as limit orders, in the given order.
+// An invalid quote, including one off the market's increments or outside the price band, rejects the whole
+// set and leaves the previous one in place. An empty set pulls all quotes. A quote the book still turns away
+// once the set is being placed, such as after an earlier quote halted the market, stops the placement and
+// its error is returned with the quotes placed so far.
+func (ob *OrderBook) MassQuote(owner string, quotes []Quote) (Orders, []Match, error) {
+	if owner == "" {
+		return nil, nil, &RejectError{Reason: RejectReasonInvalidOrder, msg: "MassQuote: missing owner"}
+	}
+	for i, quote := range quotes {
+		if quote.Placement != BID_ORDER && quote.Placement != ASK_ORDER || quote.Price <= 0 || quote.Size <= 0 {
+			return nil, nil, &RejectError{Reason: RejectReasonInvalidOrder, msg: fmt.Sprintf("MassQuote: invalid quote %d: %+v", i, quote)}
+		}
+		if err := ob.Config.CheckOrder(quote.Price, quote.Size); err != nil {
+			return nil, nil, fmt.Errorf("MassQuote: invalid quote %d: %w", i, err)
+		}
+		if err := ob.checkTradingState(quote.Price); err != nil {
+			return nil, nil, fmt.Errorf("MassQuote: invalid quote %d: %w", i, err)
+		}
+	}
+
+	for _, order := range ob.quotes[owner] {
+		if !order.Status.IsTerminal() {
+			ob.CancelOrderByID(order.ID, order.OrderPlacement, CancelReasonQuoteReplaced)
+		}
+	}
+
+	orders := make(Orders, 0, len(quotes))
+	matches := []Match{}
+	for i, quote := range quotes {
+		order := ob.newOrder(quote.Placement, quote.Size)
+		order.Owner = owner
+		order.Quote = true
+		quoteMatches, err := ob.PlaceLimitOrder(quote.Price, order)
+		matches = append(matches, quoteMatches...)
+		orders = append(orders, order)
+		if err != nil {
+			ob.quotes[owner] = orders
+			return orders, matches, fmt.Errorf("MassQuote: quote %d: %w", i, err)
+		}
+	}
+	ob.quotes[owner] = orders
+
+	return orders, matches, nil
+}
@@ -0,0 +1,91 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMassQuote(t *testing.T) {
+	Convey("When a maker mass quotes", t, func() {
+		ob := entity.NewOrderBook("test")
+		first, _, err := ob.MassQuote("maker", []entity.Quote{
+			{Placement: entity.BID_ORDER, Price: 99, Size: 5},
+			{Placement: entity.ASK_ORDER, Price: 101, Size: 5},
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Should place the quotes as limit orders of the maker", func() {
+			So(first, ShouldHaveLength, 2)
+			So(first[0].Owner, ShouldEqual, "maker")
+			So(ob.Bids()[0].Price, ShouldEqual, 99)
+			So(ob.Asks()[0].Price, ShouldEqual, 101)
+		})
+
+		Convey("Should replace the previous quote set", func() {
+			second, _, err := ob.MassQuote("maker", []entity.Quote{
+				{Placement: entity.BID_ORDER, Price: 99.5, Size: 3},
+				{Placement: entity.ASK_ORDER, Price: 100.5, Size: 3},
+			})
+
+			So(err, ShouldBeNil)
+			So(first[0].Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(first[1].CancelReason, ShouldEqual, entity.CancelReasonQuoteReplaced)
+			So(second[0].Limit.Price, ShouldEqual, 99.5)
+			So(ob.OrderCount(), ShouldEqual, 2)
+		})
+
+		Convey("Should leave other makers' quotes alone", func() {
+			ob.MassQuote("other", []entity.Quote{{Placement: entity.ASK_ORDER, Price: 102, Size: 1}})
+			ob.MassQuote("maker", nil)
+
+			So(ob.OrderCount(), ShouldEqual, 1)
+			So(ob.Asks()[0].Price, ShouldEqual, 102)
+		})
+
+		Convey("Should keep the previous set when a quote is invalid", func() {
+			_, _, err := ob.MassQuote("maker", []entity.Quote{
+				{Placement: entity.BID_ORDER, Price: 99.5, Size: 3},
+				{Placement: entity.ASK_ORDER, Price: 0, Size: 3},
+			})
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(first[0].Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.OrderCount(), ShouldEqual, 2)
+		})
+
+		Convey("Should keep the previous set when a quote is off the tick size", func() {
+			_, _, err := ob.MassQuote("maker", []entity.Quote{
+				{Placement: entity.BID_ORDER, Price: 99.505, Size: 3},
+				{Placement: entity.ASK_ORDER, Price: 100.5, Size: 3},
+			})
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidTickSize)
+			So(first[0].Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.OrderCount(), ShouldEqual, 2)
+		})
+	})
+
+	Convey("When a maker quotes outside the price band", t, func() {
+		config := entity.DefaultMarketConfig
+		config.PriceBandPct = 10
+		ob := entity.RestoreOrderBook(entity.BookSnapshot{Market: "test", LastTradePrice: 100}, config)
+		first, _, err := ob.MassQuote("maker", []entity.Quote{{Placement: entity.BID_ORDER, Price: 99, Size: 5}})
+		So(err, ShouldBeNil)
+
+		Convey("Should keep the previous set", func() {
+			_, _, err := ob.MassQuote("maker", []entity.Quote{
+				{Placement: entity.BID_ORDER, Price: 98, Size: 5},
+				{Placement: entity.ASK_ORDER, Price: 120, Size: 5},
+			})
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonPriceOutOfBand)
+			So(first[0].Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.OrderCount(), ShouldEqual, 1)
+		})
+	})
+}
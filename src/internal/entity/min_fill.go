@@ -0,0 +1,41 @@
+package entity
+
+import "fmt"
+
+// checkMinFillSize rejects the order if its MinFillSize is invalid, or if the resting orders it can trade
+// with up to limitPrice, zero for any price, can't fill at least MinFillSize in executions of that size.
+func (ob *OrderBook) checkMinFillSize(order *Order, limitPrice float64) error {
+	if err := ob.minFillSizeError(order, order.Size, limitPrice); err != nil {
+		order.Transition(OrderStatusRejected)
+		return fmt.Errorf("checkMinFillSize: %w", err)
+	}
+	return nil
+}
+
+// minFillSizeError is checkMinFillSize for the order at size, leaving the order as it is.
+func (ob *OrderBook) minFillSizeError(order *Order, size, limitPrice float64) error {
+	if order.MinFillSize == 0 {
+		return nil
+	}
+	if order.MinFillSize < 0 || order.MinFillSize > size {
+		return &RejectError{Reason: RejectReasonInvalidOrder, msg: fmt.Sprintf("invalid min fill size %.2f for size %.2f", order.MinFillSize, size)}
+	}
+
+	available := 0.0
+	ob.levels(order.OrderPlacement.Opposite()).walk(func(_ int64, limit *Limit) bool {
+		if limitPrice > 0 && !ob.crosses(order.OrderPlacement, limitPrice, limit.Price) {
+			return false
+		}
+		for _, resting := range limit.Orders.All() {
+			if resting.Size >= order.MinFillSize {
+				available = addSize(available, resting.Size)
+			}
+		}
+		return available < order.MinFillSize
+	})
+
+	if available < order.MinFillSize {
+		return &RejectError{Reason: RejectReasonInsufficientLiquidity, msg: fmt.Sprintf("only %.2f available in fills of at least %.2f", available, order.MinFillSize)}
+	}
+	return nil
+}
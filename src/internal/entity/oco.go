@@ -0,0 +1,79 @@
+package entity
+
+import "fmt"
+
+/*
+	One-cancels-other (OCO) pairs link two orders, typically a take-profit limit and a stop-loss on the
+	same side: as soon as either leg fills, triggers, or leaves the book, the other one is cancelled.
+*/
+
+// OCOLeg is one order of an OCO pair, placed as by Place.
+type OCOLeg struct {
+	Type  OrderType
+	Price float64
+	Order *Order
+}
+
+// PlaceOCOOrder places two linked orders of the same side. The first leg is placed first; if it already
+// fills or is rejected, the second one is cancelled instead of being placed.
+func (ob *OrderBook) PlaceOCOOrder(first, second OCOLeg) ([]Match, error) {
+	var invalid string
+	if !isOCOLegType(first.Type) {
+		invalid = fmt.Sprintf("invalid OCO leg type %q", first.Type)
+	} else if !isOCOLegType(second.Type) {
+		invalid = fmt.Sprintf("invalid OCO leg type %q", second.Type)
+	} else if first.Order.OrderPlacement != second.Order.OrderPlacement {
+		invalid = "OCO legs must be on the same side"
+	}
+	if invalid != "" {
+		ob.accept(first.Order)
+		ob.accept(second.Order)
+		reject(first.Order, RejectReasonInvalidOrder, "PlaceOCOOrder: %s", invalid)
+		return nil, reject(second.Order, RejectReasonInvalidOrder, "PlaceOCOOrder: %s", invalid)
+	}
+
+	first.Order.LinkedOrderID, second.Order.LinkedOrderID = second.Order.ID, first.Order.ID
+	first.Order.ocoSibling, second.Order.ocoSibling = second.Order, first.Order
+
+	matches, err := ob.Place(first.Type, first.Price, first.Order)
+	if first.Order.ocoSibling == nil {
+		ob.accept(second.Order)
+		second.Order.Cancel(CancelReasonOCOSibling)
+		return matches, err
+	}
+
+	secondMatches, err := ob.Place(second.Type, second.Price, second.Order)
+	return append(matches, secondMatches...), err
+}
+
+// isOCOLegType reports whether orders of the type can wait on the book, which a market order can't.
+func isOCOLegType(orderType OrderType) bool {
+	return orderType == LimitOrder || orderType == StopOrder || orderType == StopLimitOrder
+}
+
+// cancelSibling unlinks the order from its OCO sibling and cancels the sibling if it's live.
+// A sibling that isn't placed yet is left for PlaceOCOOrder to cancel.
+func (ob *OrderBook) cancelSibling(order *Order) {
+	sibling := order.ocoSibling
+	if sibling == nil {
+		return
+	}
+	order.ocoSibling, sibling.ocoSibling = nil, nil
+
+	if _, live := ob.LookupOrder(sibling.ID); live {
+		ob.CancelOrderByID(sibling.ID, sibling.OrderPlacement, CancelReasonOCOSibling)
+	}
+}
+
+// relinkSiblings restores the OCO links between restored orders.
+func relinkSiblings(orders Orders) {
+	byID := make(map[int64]*Order, len(orders))
+	for _, order := range orders {
+		byID[order.ID] = order
+	}
+	for _, order := range orders {
+		if sibling, exists := byID[order.LinkedOrderID]; exists {
+			order.ocoSibling = sibling
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package entity_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOCOOrder(t *testing.T) {
+	Convey("When placing an OCO pair", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 10))
+		ob.PlaceLimitOrder(99, entity.NewOrder(entity.BID_ORDER, 10))
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+
+		takeProfit := entity.NewOrder(entity.ASK_ORDER, 2)
+		stopLoss := entity.NewOrder(entity.ASK_ORDER, 2)
+		_, err := ob.PlaceOCOOrder(
+			entity.OCOLeg{Type: entity.LimitOrder, Price: 110, Order: takeProfit},
+			entity.OCOLeg{Type: entity.StopOrder, Price: 95, Order: stopLoss},
+		)
+		So(err, ShouldBeNil)
+
+		Convey("Should link both legs", func() {
+			So(takeProfit.LinkedOrderID, ShouldEqual, stopLoss.ID)
+			So(stopLoss.LinkedOrderID, ShouldEqual, takeProfit.ID)
+			So(ob.StopOrders(), ShouldResemble, entity.Orders{stopLoss})
+			So(ob.AskTotalVolume(), ShouldEqual, 11)
+		})
+
+		Convey("Should cancel the stop when the limit leg fills", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 10))
+
+			So(takeProfit.Status, ShouldEqual, entity.OrderStatusPartiallyFilled)
+			So(stopLoss.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(stopLoss.CancelReason, ShouldEqual, entity.CancelReasonOCOSibling)
+			So(ob.StopOrders(), ShouldBeEmpty)
+		})
+
+		Convey("Should cancel the limit leg when the stop triggers", func() {
+			ob.PlaceLimitOrder(95, entity.NewOrder(entity.BID_ORDER, 10))
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 12))
+
+			So(stopLoss.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(takeProfit.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(takeProfit.CancelReason, ShouldEqual, entity.CancelReasonOCOSibling)
+			So(ob.AskTotalVolume(), ShouldEqual, 9)
+		})
+
+		Convey("Should cancel the sibling when one leg is cancelled", func() {
+			So(ob.Cancel(takeProfit.ID), ShouldBeNil)
+
+			So(stopLoss.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(ob.StopOrders(), ShouldBeEmpty)
+		})
+
+		Convey("Should keep the link across snapshots", func() {
+			var buf bytes.Buffer
+			So(entity.EncodeSnapshot(&buf, ob.Snapshot()), ShouldBeNil)
+			snapshot, err := entity.DecodeSnapshot(&buf)
+			So(err, ShouldBeNil)
+			restored := entity.RestoreOrderBook(snapshot, entity.DefaultMarketConfig)
+
+			So(restored.Cancel(stopLoss.ID), ShouldBeNil)
+
+			So(restored.AskTotalVolume(), ShouldEqual, 9)
+		})
+	})
+
+	Convey("When an OCO pair is invalid", t, func() {
+		ob := entity.NewOrderBook("test")
+		first := entity.NewOrder(entity.ASK_ORDER, 1)
+		second := entity.NewOrder(entity.BID_ORDER, 1)
+
+		_, err := ob.PlaceOCOOrder(
+			entity.OCOLeg{Type: entity.LimitOrder, Price: 110, Order: first},
+			entity.OCOLeg{Type: entity.StopOrder, Price: 95, Order: second},
+		)
+
+		Convey("Should reject both legs", func() {
+			reason, rejected := entity.RejectReasonOf(err)
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(first.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(second.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(ob.OrderCount(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("When the second leg is rejected", t, func() {
+		ob := entity.NewOrderBook("test")
+		first := entity.NewOrder(entity.ASK_ORDER, 1)
+		second := entity.NewOrder(entity.ASK_ORDER, 1)
+
+		_, err := ob.PlaceOCOOrder(
+			entity.OCOLeg{Type: entity.LimitOrder, Price: 110, Order: first},
+			entity.OCOLeg{Type: entity.StopOrder, Price: 0, Order: second},
+		)
+
+		Convey("Should cancel the first leg", func() {
+			So(err, ShouldNotBeNil)
+			So(second.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(first.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(ob.OrderCount(), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package entity
+
+import "container/list"
+
+// OrderQueue holds the orders resting at a price level in strict arrival (FIFO) order.
+// Each order keeps a handle to its own node so removal doesn't disturb the order of the others.
+type OrderQueue struct {
+	list *list.List
+}
+
+func NewOrderQueue() *OrderQueue {
+	return &OrderQueue{list: list.New()}
+}
+
+// Push queues the order behind every order with a lower arrival sequence.
+func (q *OrderQueue) Push(o *Order) {
+	e := q.list.Back()
+	for e != nil && e.Value.(*Order).ArrivalSequence > o.ArrivalSequence {
+		e = e.Prev()
+	}
+
+	if e == nil {
+		o.node = q.list.PushFront(o)
+	} else {
+		o.node = q.list.InsertAfter(o, e)
+	}
+}
+
+func (q *OrderQueue) Remove(o *Order) {
+	if o.node == nil {
+		return
+	}
+	q.list.Remove(o.node)
+	o.node = nil
+}
+
+// Front returns the order with the highest time priority, or nil if the queue is empty.
+func (q *OrderQueue) Front() *Order {
+	front := q.list.Front()
+	if front == nil {
+		return nil
+	}
+	return front.Value.(*Order)
+}
+
+// Next returns the order queued right behind o, or nil if o is the last one or no longer queued.
+func (q *OrderQueue) Next(o *Order) *Order {
+	if o.node == nil || o.node.Next() == nil {
+		return nil
+	}
+	return o.node.Next().Value.(*Order)
+}
+
+func (q *OrderQueue) Len() int {
+	return q.list.Len()
+}
+
+// All returns the queued orders from oldest to newest.
+func (q *OrderQueue) All() Orders {
+	orders := make(Orders, 0, q.list.Len())
+	for e := q.list.Front(); e != nil; e = e.Next() {
+		orders = append(orders, e.Value.(*Order))
+	}
+	return orders
+}
@@ -0,0 +1,134 @@
+package entity
+
+import "fmt"
+
+/*
+	Order lifecycle:
+
+	NEW ──► PARTIALLY_FILLED ──► FILLED
+	 │              │
+	 │              └──► CANCELLED / EXPIRED
+	 └──► FILLED / CANCELLED / EXPIRED / REJECTED
+*/
+
+type OrderStatus string
+
+const (
+	OrderStatusNew             OrderStatus = "NEW"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusCancelled       OrderStatus = "CANCELLED"
+	OrderStatusExpired         OrderStatus = "EXPIRED"
+	OrderStatusRejected        OrderStatus = "REJECTED"
+)
+
+// CancelReason records why an order left the book without being filled.
+type CancelReason string
+
+const (
+	CancelReasonUserRequested       CancelReason = "USER_REQUESTED"
+	CancelReasonIOCRemainder        CancelReason = "IOC_REMAINDER"
+	CancelReasonExpired             CancelReason = "EXPIRED"
+	CancelReasonSelfTradePrevention CancelReason = "SELF_TRADE_PREVENTION"
+	CancelReasonKillSwitch          CancelReason = "KILL_SWITCH"
+	CancelReasonAdmin               CancelReason = "ADMIN"
+	CancelReasonDust                CancelReason = "DUST"
+
+	// CancelReasonInsufficientLiquidity cancels the unfilled remainder of a partially fillable market order
+	CancelReasonInsufficientLiquidity CancelReason = "INSUFFICIENT_LIQUIDITY_PARTIAL"
+	// CancelReasonMaxSweepDepth cancels the remainder of a taking order that reached the market's max sweep depth
+	CancelReasonMaxSweepDepth CancelReason = "MAX_SWEEP_DEPTH"
+	// CancelReasonPriceProtection cancels the remainder of a market order once the next fill would pass its protection price
+	CancelReasonPriceProtection CancelReason = "PRICE_PROTECTION"
+	// A market order reached past the price band and halted the market
+	CancelReasonCircuitBreaker CancelReason = "CIRCUIT_BREAKER"
+	// CancelReasonQuoteReplaced cancels the quotes of a maker's previous mass quote
+	CancelReasonQuoteReplaced CancelReason = "QUOTE_REPLACED"
+	// CancelReasonMinFillSize cancels the remainder of an order that would rest crossing orders too small for its MinFillSize
+	CancelReasonMinFillSize CancelReason = "MIN_FILL_SIZE"
+	// CancelReasonOCOSibling cancels the other order of an OCO pair once one of them fills, triggers or is cancelled
+	CancelReasonOCOSibling CancelReason = "OCO_SIBLING"
+)
+
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew: {
+		OrderStatusPartiallyFilled,
+		OrderStatusFilled,
+		OrderStatusCancelled,
+		OrderStatusExpired,
+		OrderStatusRejected,
+	},
+	OrderStatusPartiallyFilled: {
+		OrderStatusFilled,
+		OrderStatusCancelled,
+		OrderStatusExpired,
+	},
+}
+
+// OrderTransition is emitted every time an order changes status.
+type OrderTransition struct {
+	Order *Order
+	From  OrderStatus
+	To    OrderStatus
+	// Sequence is the book's mutation sequence number of the status change
+	Sequence int64
+}
+
+// IsTerminal reports whether no further transition is possible from the status.
+func (s OrderStatus) IsTerminal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+func (s OrderStatus) canTransitionTo(to OrderStatus) bool {
+	for _, status := range orderTransitions[s] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the order to the given status and notifies the owning book.
+// Staying PARTIALLY_FILLED after another partial fill is a no-op.
+func (o *Order) Transition(to OrderStatus) error {
+	from := o.Status
+	if from == to && to == OrderStatusPartiallyFilled {
+		return nil
+	}
+	if !from.canTransitionTo(to) {
+		return fmt.Errorf("Transition: invalid order status transition from %s to %s for order %d", from, to, o.ID)
+	}
+
+	o.Status = to
+	if o.onTransition != nil {
+		o.onTransition(OrderTransition{Order: o, From: from, To: to})
+	}
+
+	return nil
+}
+
+// Cancel moves the order to CANCELLED, or EXPIRED for CancelReasonExpired, recording the reason.
+func (o *Order) Cancel(reason CancelReason) error {
+	to := OrderStatusCancelled
+	if reason == CancelReasonExpired {
+		to = OrderStatusExpired
+	}
+
+	if !o.Status.canTransitionTo(to) {
+		return fmt.Errorf("Cancel: order %d is already %s", o.ID, o.Status)
+	}
+
+	o.CancelReason = reason
+	return o.Transition(to)
+}
+
+// fill reduces the remaining size and moves the order to PARTIALLY_FILLED or FILLED accordingly.
+func (o *Order) fill(size float64) {
+	o.Size = subSize(o.Size, size)
+	o.FilledSize = addSize(o.FilledSize, size)
+	if o.Size == 0.0 {
+		o.Transition(OrderStatusFilled)
+	} else {
+		o.Transition(OrderStatusPartiallyFilled)
+	}
+}
@@ -0,0 +1,86 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOrderTransition(t *testing.T) {
+	Convey("When transitioning order status", t, func() {
+		Convey("Should allow NEW to PARTIALLY_FILLED to FILLED", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 10)
+
+			So(order.Status, ShouldEqual, entity.OrderStatusNew)
+			So(order.Transition(entity.OrderStatusPartiallyFilled), ShouldBeNil)
+			So(order.Transition(entity.OrderStatusPartiallyFilled), ShouldBeNil)
+			So(order.Transition(entity.OrderStatusFilled), ShouldBeNil)
+			So(order.Status.IsTerminal(), ShouldBeTrue)
+		})
+
+		Convey("Should reject transitions out of a terminal status", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 10)
+			So(order.Transition(entity.OrderStatusCancelled), ShouldBeNil)
+
+			err := order.Transition(entity.OrderStatusFilled)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "invalid order status transition from CANCELLED to FILLED")
+			So(order.Status, ShouldEqual, entity.OrderStatusCancelled)
+		})
+
+		Convey("Should expire orders cancelled for expiration", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 10)
+
+			So(order.Cancel(entity.CancelReasonExpired), ShouldBeNil)
+			So(order.Status, ShouldEqual, entity.OrderStatusExpired)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonExpired)
+		})
+
+		Convey("Should reject PARTIALLY_FILLED back to NEW", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 10)
+			So(order.Transition(entity.OrderStatusPartiallyFilled), ShouldBeNil)
+
+			So(order.Transition(entity.OrderStatusNew), ShouldNotBeNil)
+		})
+	})
+
+	Convey("When matching orders in a book", t, func() {
+		ob := entity.NewOrderBook("test")
+		transitions := []entity.OrderTransition{}
+		ob.OnTransition = func(transition entity.OrderTransition) {
+			transitions = append(transitions, transition)
+		}
+
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_000, sellOrder)
+		buyOrder := entity.NewOrder(entity.BID_ORDER, 4)
+		ob.PlaceMarketOrder(buyOrder)
+
+		Convey("Should update statuses and emit transition events", func() {
+			So(sellOrder.Status, ShouldEqual, entity.OrderStatusPartiallyFilled)
+			So(buyOrder.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(len(transitions), ShouldEqual, 2)
+			So(transitions[0].Order, ShouldEqual, sellOrder)
+			So(transitions[0].To, ShouldEqual, entity.OrderStatusPartiallyFilled)
+			So(transitions[1].Order, ShouldEqual, buyOrder)
+			So(transitions[1].To, ShouldEqual, entity.OrderStatusFilled)
+		})
+
+		Convey("Should mark rejected market orders", func() {
+			bigOrder := entity.NewOrder(entity.BID_ORDER, 100)
+			_, err := ob.PlaceMarketOrder(bigOrder)
+
+			So(err, ShouldNotBeNil)
+			So(bigOrder.Status, ShouldEqual, entity.OrderStatusRejected)
+		})
+
+		Convey("Should mark cancelled orders", func() {
+			So(ob.CancelOrderByID(sellOrder.ID, entity.ASK_ORDER, entity.CancelReasonUserRequested), ShouldBeNil)
+			So(sellOrder.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(sellOrder.CancelReason, ShouldEqual, entity.CancelReasonUserRequested)
+			So(transitions[len(transitions)-1].Order.CancelReason, ShouldEqual, entity.CancelReasonUserRequested)
+		})
+	})
+}
@@ -1,12 +1,11 @@
 package entity
 
 import (
+	"container/list"
 	"errors"
 	"fmt"
-	"sort"
+	"sync/atomic"
 	"time"
-
-	"github.com/palantir/stacktrace"
 )
 
 /*
@@ -23,6 +22,9 @@ type OrderType string
 const (
 	MarketOrder OrderType = "MARKET_ORDER"
 	LimitOrder  OrderType = "LIMIT_ORDER"
+	StopOrder   OrderType = "STOP_ORDER"
+	// StopLimitOrder places a limit order at the limit price once the stop price is crossed
+	StopLimitOrder OrderType = "STOP_LIMIT_ORDER"
 )
 
 type OrderPlacement string
@@ -32,48 +34,124 @@ const (
 	ASK_ORDER OrderPlacement = "ASK"
 )
 
+func (p OrderPlacement) Opposite() OrderPlacement {
+	if p == BID_ORDER {
+		return ASK_ORDER
+	}
+	return BID_ORDER
+}
+
 type Match struct {
 	Ask        *Order
 	Bid        *Order
 	SizeFilled float64
 	Price      float64
+	// Sequence is the book's mutation sequence number of the fill
+	Sequence int64
 }
 
 type Order struct {
 	ID             int64          `json:"id"`
 	OrderPlacement OrderPlacement `json:"order_placement"`
 	Size           float64        `json:"size"`
+	FilledSize     float64        `json:"filled_size"`
+	Status         OrderStatus    `json:"status"`
+	CancelReason   CancelReason   `json:"cancel_reason,omitempty"`
+	StopPrice      float64        `json:"stop_price,omitempty"`
+	LimitPrice     float64        `json:"limit_price,omitempty"`
 	Limit          *Limit         `json:"-"`
 	Timestamp      int64          `json:"timestamp"`
+
+	// Owner identifies the user who placed the order
+	Owner string `json:"owner,omitempty"`
+	// Quote marks an order placed by its owner's mass quote, replaced by their next one
+	Quote bool `json:"quote,omitempty"`
+	// MinFillSize is the smallest execution the order accepts while taking liquidity on entry. The order is
+	// rejected if the book can't fill at least that much, and a remainder that would rest crossing the orders
+	// it skipped is cancelled. Once resting, it fills normally.
+	MinFillSize float64 `json:"min_fill_size,omitempty"`
+	// SelfTradePrevention decides what happens when the order would match a resting order of the same owner.
+	// Self-trades are allowed when it's unset.
+	SelfTradePrevention STPMode `json:"self_trade_prevention,omitempty"`
+
+	// ArrivalSequence is assigned by the book on acceptance and decides time priority within a price level,
+	// since wall-clock timestamps can collide or go backwards.
+	ArrivalSequence int64 `json:"arrival_sequence"`
+
+	// AllowPartialFill lets a market order fill whatever liquidity is available instead of being rejected,
+	// cancelling the remainder.
+	AllowPartialFill bool `json:"allow_partial_fill"`
+
+	TimeInForce TimeInForce `json:"time_in_force,omitempty"`
+
+	// PostOnly makes a limit order maker-only: it's rejected instead of taking liquidity when it would cross.
+	PostOnly bool `json:"post_only,omitempty"`
+
+	// ExpiresAt makes the order good-till-date: it's expired by the book once this time (in Unix nanoseconds)
+	// passes. Zero means it never expires.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+
+	// ProtectionPrice bounds how far a market order may fill: matching stops at levels worse than it.
+	ProtectionPrice float64 `json:"protection_price,omitempty"`
+	// MaxSlippagePct bounds a market order to this percentage away from the best opposite price on entry.
+	MaxSlippagePct float64 `json:"max_slippage_pct,omitempty"`
+
+	// LinkedOrderID is the other order of an OCO pair
+	LinkedOrderID int64 `json:"linked_order_id,omitempty"`
+
+	// Bracket holds the exits sprung once this entry order completes
+	Bracket *Bracket `json:"bracket,omitempty"`
+	// ParentOrderID is the entry order of a bracket exit
+	ParentOrderID int64 `json:"parent_order_id,omitempty"`
+
+	// Pegged makes the book re-peg the order to the best price of its side plus PegOffset as the BBO moves
+	Pegged    bool    `json:"pegged,omitempty"`
+	PegOffset float64 `json:"peg_offset,omitempty"`
+
+	node         *list.Element
+	onTransition func(OrderTransition)
+	ocoSibling   *Order
+	// expiryIndex is the order's position in its book's expiry queue plus one, zero while it isn't queued
+	expiryIndex int
 }
 
 type Orders []*Order
 
-var orderIdSequence int64 = 0
-
-func (o Orders) Len() int           { return len(o) }
-func (o Orders) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
-func (o Orders) Less(i, j int) bool { return o[i].Timestamp < o[j].Timestamp }
+// orderIdSequence is shared by every book, so it's only accessed atomically
+var orderIdSequence atomic.Int64
 
-type OrderMetadata struct {
-	Order  *Order
-	Market string
+// raiseOrderIDSequence makes sure new orders get IDs above id.
+func raiseOrderIDSequence(id int64) {
+	for {
+		current := orderIdSequence.Load()
+		if current >= id || orderIdSequence.CompareAndSwap(current, id) {
+			return
+		}
+	}
 }
 
-var OrderIndex = make(map[int64]OrderMetadata)
+// NextOrderID takes the next ID of the order ID sequence shared by every book.
+func NextOrderID() int64 {
+	return orderIdSequence.Add(1)
+}
 
+// NewOrder creates an order with the next ID of the shared sequence, timestamped now.
 func NewOrder(orderPlacement OrderPlacement, size float64) *Order {
-	orderIdSequence += 1
+	return newOrder(NextOrderID(), orderPlacement, size, time.Now())
+}
+
+func newOrder(id int64, orderPlacement OrderPlacement, size float64, timestamp time.Time) *Order {
 	return &Order{
-		ID:             orderIdSequence,
-		Size:           size,
+		ID:             id,
+		Size:           RoundSize(size),
 		OrderPlacement: orderPlacement,
-		Timestamp:      time.Now().UnixNano(),
+		Status:         OrderStatusNew,
+		Timestamp:      timestamp.UnixNano(),
 	}
 }
 
 func (o *Order) IsFilled() bool {
-	return o.Size == 0.0
+	return o.Status == OrderStatusFilled
 }
 
 func (o *Order) String() string {
@@ -82,73 +160,74 @@ func (o *Order) String() string {
 
 type Limit struct {
 	Price       float64
-	Orders      Orders
+	Orders      *OrderQueue
 	TotalVolume float64
-}
 
-type Limits []*Limit
-
-type ByBestAsk struct{ Limits }
-
-func (a ByBestAsk) Len() int           { return len(a.Limits) }
-func (a ByBestAsk) Swap(i, j int)      { a.Limits[i], a.Limits[j] = a.Limits[j], a.Limits[i] }
-func (a ByBestAsk) Less(i, j int) bool { return a.Limits[i].Price < a.Limits[j].Price }
-
-type ByBestBid struct{ Limits }
-
-func (a ByBestBid) Len() int           { return len(a.Limits) }
-func (a ByBestBid) Swap(i, j int)      { a.Limits[i], a.Limits[j] = a.Limits[j], a.Limits[i] }
-func (a ByBestBid) Less(i, j int) bool { return a.Limits[i].Price > a.Limits[j].Price }
+	onChange func()
+}
 
 func NewLimit(price float64) *Limit {
 	return &Limit{
 		Price:  price,
-		Orders: []*Order{},
+		Orders: NewOrderQueue(),
 	}
 }
 
 func (l *Limit) AddOrder(o *Order) {
 	o.Limit = l
-	l.Orders = append(l.Orders, o)
-	l.TotalVolume += o.Size
+	l.Orders.Push(o)
+	l.TotalVolume = addSize(l.TotalVolume, o.Size)
+	l.changed()
 }
 
-func (l *Limit) DeleteOrder(o *Order) {
-	for i := 0; i < len(l.Orders); i++ {
-		if l.Orders[i] == o {
-			// Copy last element to current index to overwrite it and then trim the last element to free up memory
-			l.Orders[i] = l.Orders[len(l.Orders)-1]
-			l.Orders = l.Orders[:len(l.Orders)-1]
-			break
-		}
-	}
+// IsEmpty reports whether no orders rest at this level anymore.
+func (l *Limit) IsEmpty() bool {
+	return l.Orders.Len() == 0
+}
 
+func (l *Limit) DeleteOrder(o *Order) {
+	l.Orders.Remove(o)
 	o.Limit = nil
-	l.TotalVolume -= o.Size
-	sort.Sort(l.Orders)
+	l.TotalVolume = subSize(l.TotalVolume, o.Size)
+	l.changed()
 }
 
-func (l *Limit) Fill(order *Order) []Match {
-	ordersToDelete := []*Order{} // Avoid messing up with order loop
+// changed notifies the owning book that the level's size changed.
+func (l *Limit) changed() {
+	if l.onChange != nil {
+		l.onChange()
+	}
+}
 
+// Fill matches the order against the resting orders from the front of the queue, oldest first,
+// until the order is filled or cancelled by self-trade prevention. Resting orders that would fill less
+// than the order's MinFillSize are skipped.
+func (l *Limit) Fill(order *Order) []Match {
 	matches := []Match{}
-	for _, matchingOrder := range l.Orders {
-		match := l.fillOrder(matchingOrder, order)
-		matches = append(matches, match)
+	for matchingOrder := l.Orders.Front(); matchingOrder != nil && !order.Status.IsTerminal(); {
+		next := l.Orders.Next(matchingOrder)
 
-		// Remove filled order from limit's entry
-		if matchingOrder.IsFilled() {
-			ordersToDelete = append(ordersToDelete, matchingOrder)
+		if order.MinFillSize > 0 && min(matchingOrder.Size, order.Size) < order.MinFillSize {
+			matchingOrder = next
+			continue
 		}
 
-		// Stop looking for matches
-		if order.IsFilled() {
-			break
+		if isSelfTrade(matchingOrder, order) {
+			l.preventSelfTrade(matchingOrder, order)
+		} else {
+			matches = append(matches, l.fillOrder(matchingOrder, order))
+
+			// Remove filled order from limit's entry
+			if matchingOrder.IsFilled() {
+				l.DeleteOrder(matchingOrder)
+			}
 		}
-	}
 
-	for _, orderToDelete := range ordersToDelete {
-		l.DeleteOrder(orderToDelete)
+		// Orders leaving the queue can take their OCO sibling with them
+		if next != nil && next.Limit != l {
+			next = l.Orders.Front()
+		}
+		matchingOrder = next
 	}
 
 	return matches
@@ -164,9 +243,10 @@ func (l *Limit) fillOrder(matchingOrder, order *Order) Match {
 	}
 
 	sizeFilled := min(ask.Size, bid.Size)
-	ask.Size -= sizeFilled
-	bid.Size -= sizeFilled
-	l.TotalVolume -= sizeFilled
+	ask.fill(sizeFilled)
+	bid.fill(sizeFilled)
+	l.TotalVolume = subSize(l.TotalVolume, sizeFilled)
+	l.changed()
 
 	return Match{
 		Ask:        ask,
@@ -180,66 +260,295 @@ func (l *Limit) String() string {
 	return fmt.Sprintf("[price: %.2f | volume: %.2f]", l.Price, l.TotalVolume)
 }
 
+// OrderBook matches the orders of one market. It isn't safe for concurrent use, so callers serialize
+// access to each book; the order ID sequence it shares with other books is synchronized.
 type OrderBook struct {
 	Market string
-
-	asks []*Limit
-	bids []*Limit
-
-	AskLimits map[float64]*Limit
-	BidLimits map[float64]*Limit
+	Config MarketConfig
+
+	// OnTransition, when set, is called for every status change of orders accepted by this book.
+	OnTransition func(OrderTransition)
+	// OnMatch, when set, is called for every match as it is executed.
+	OnMatch func(Match)
+	// OnBBOChange, when set, is called with the new best bid and offer once an operation moved them.
+	OnBBOChange func(BBO)
+	// OnLevelChange, when set, is called for every price level an operation added, resized or removed,
+	// once the operation completes.
+	OnLevelChange func(LevelDelta)
+
+	// Clock and NextOrderID, when set, replace time.Now and the shared order ID sequence for what the book
+	// decides itself: when a halt ends and the timestamps and IDs of the orders it creates, such as bracket
+	// exits. Setting both makes the book deterministic.
+	Clock       func() time.Time
+	NextOrderID func() int64
+
+	asks *priceLevels
+	bids *priceLevels
+
+	arrivalSequence int64
+	// mutationSequence numbers every place, amend, fill and status change of the book, in the order they happen
+	mutationSequence int64
+
+	// orders indexes the live orders of this book by ID, from acceptance until they reach a terminal status
+	orders map[int64]*Order
+
+	stops           stopIndex
+	expiries        expiryQueue
+	quotes          map[string]Orders
+	pegged          Orders
+	bbo             BBO
+	lastTradePrice  float64
+	triggeringStops bool
+	sweeping        bool
+	notifyingBBO    bool
+	// auction makes orders rest without matching until the book is uncrossed
+	auction bool
+	// haltedUntil is when a halted market resumes, in Unix nanoseconds, or zero
+	haltedUntil int64
+
+	// Levels changed by the running operation, and whether each existed before it
+	changedLevels map[levelKey]bool
+
+	// Completed bracket entries waiting for their exits to be placed
+	pendingBrackets   Orders
+	springingBrackets bool
+
+	// Limits are keyed by price in ticks, so prices that round to the same tick share a level
+	AskLimits map[int64]*Limit
+	BidLimits map[int64]*Limit
 }
 
 func NewOrderBook(market string) *OrderBook {
+	return NewOrderBookWithConfig(market, DefaultMarketConfig)
+}
+
+func NewOrderBookWithConfig(market string, config MarketConfig) *OrderBook {
 	return &OrderBook{
 		Market:    market,
-		asks:      []*Limit{},
-		bids:      []*Limit{},
-		AskLimits: make(map[float64]*Limit),
-		BidLimits: make(map[float64]*Limit),
+		Config:    config,
+		asks:      newPriceLevels(false),
+		bids:      newPriceLevels(true),
+		AskLimits: make(map[int64]*Limit),
+		BidLimits: make(map[int64]*Limit),
+		orders:    make(map[int64]*Order),
+		quotes:    make(map[string]Orders),
+	}
+}
+
+// LookupOrder returns a live order of this book: resting, stopped or being matched.
+func (ob *OrderBook) LookupOrder(orderId int64) (*Order, bool) {
+	order, exists := ob.orders[orderId]
+	return order, exists
+}
+
+// OrderIndexSize returns the number of live orders, which should match the resting and stop orders plus
+// any order currently being matched. Steady growth beyond that indicates a leak.
+func (ob *OrderBook) OrderIndexSize() int {
+	return len(ob.orders)
+}
+
+// Place places a limit, market, stop or stop-limit order. price is the stop price for stop and stop-limit
+// orders, whose limit price is taken from order.LimitPrice.
+// Stop orders never match on entry, so they return no matches.
+func (ob *OrderBook) Place(orderType OrderType, price float64, order *Order) ([]Match, error) {
+	switch orderType {
+	case LimitOrder:
+		return ob.PlaceLimitOrder(price, order)
+	case MarketOrder:
+		return ob.PlaceMarketOrder(order)
+	case StopOrder:
+		return []Match{}, ob.PlaceStopOrder(price, order)
+	case StopLimitOrder:
+		return []Match{}, ob.PlaceStopLimitOrder(price, order.LimitPrice, order)
+	default:
+		ob.accept(order)
+		return nil, reject(order, RejectReasonInvalidOrder, "Place: invalid order type %q", orderType)
 	}
 }
 
+// PlaceMarketOrder fills the order against the best opposite levels. Unless it allows partial fills or is
+// IOC, it's rejected when the opposite side can't fill it completely.
 func (ob *OrderBook) PlaceMarketOrder(order *Order) ([]Match, error) {
-	matches := []Match{}
+	ob.accept(order)
 
-	if order.OrderPlacement == BID_ORDER {
-		if order.Size > ob.AskTotalVolume() {
-			return nil, stacktrace.NewError("PlaceMarketOrder: not enough ask volume in the market. asks: %.2f, bids: %.2f", ob.AskTotalVolume(), order.Size)
-		}
-		for _, limit := range ob.Asks() {
-			limitMatches := limit.Fill(order)
-			if limit.TotalVolume == 0 {
-				ob.deleteLimit(ASK_ORDER, limit)
+	if err := ob.Config.CheckOrder(0, order.Size); err != nil {
+		order.Transition(OrderStatusRejected)
+		return nil, fmt.Errorf("PlaceMarketOrder: %w", err)
+	}
+	if !order.TimeInForce.IsValid() {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceMarketOrder: invalid time in force %q", order.TimeInForce)
+	}
+	if !order.SelfTradePrevention.IsValid() {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceMarketOrder: invalid self-trade prevention %q", order.SelfTradePrevention)
+	}
+	if err := ob.checkMinFillSize(order, ob.protectionPrice(order)); err != nil {
+		return nil, err
+	}
+	if order.PostOnly {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceMarketOrder: market order can't be post-only")
+	}
+	if ob.auction {
+		return nil, reject(order, RejectReasonAuctionInProgress, "PlaceMarketOrder: %s is in an auction", ob.Market)
+	}
+	if err := ob.checkTradingState(0); err != nil {
+		order.Transition(OrderStatusRejected)
+		return nil, fmt.Errorf("PlaceMarketOrder: %w", err)
+	}
+	if order.ProtectionPrice < 0 || order.MaxSlippagePct < 0 {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceMarketOrder: invalid price protection %.2f / %.2f%%", order.ProtectionPrice, order.MaxSlippagePct)
+	}
+
+	if !order.AllowPartialFill && order.TimeInForce != TimeInForceIOC {
+		if order.OrderPlacement == BID_ORDER {
+			if order.Size > ob.AskTotalVolume() {
+				return nil, reject(order, RejectReasonInsufficientLiquidity, "PlaceMarketOrder: not enough ask volume in the market. asks: %.2f, bids: %.2f", ob.AskTotalVolume(), order.Size)
 			}
-			matches = append(matches, limitMatches...)
-			if order.IsFilled() {
-				break
+		} else {
+			if order.Size > ob.BidTotalVolume() {
+				return nil, reject(order, RejectReasonInsufficientLiquidity, "PlaceMarketOrder: not enough bid volume in the market. asks: %.2f, bids: %.2f", order.Size, ob.BidTotalVolume())
 			}
 		}
-	} else {
-		if order.Size > ob.BidTotalVolume() {
-			return nil, stacktrace.NewError("PlaceMarketOrder: not enough bid volume in the market. asks: %.2f, bids: %.2f", order.Size, ob.BidTotalVolume())
+	}
+
+	protectionPrice := ob.protectionPrice(order)
+	limitPrice, banded := ob.bandPrice(order.OrderPlacement, protectionPrice)
+	matches, depthReached := ob.sweep(order, limitPrice)
+	if !order.Status.IsTerminal() {
+		best := ob.bestLimit(order.OrderPlacement.Opposite())
+		if depthReached {
+			order.Cancel(CancelReasonMaxSweepDepth)
+		} else if banded && best != nil && !ob.crosses(order.OrderPlacement, limitPrice, best.Price) {
+			ob.halt()
+			order.Cancel(CancelReasonCircuitBreaker)
+		} else if protectionPrice > 0 && best != nil && !ob.crosses(order.OrderPlacement, protectionPrice, best.Price) {
+			order.Cancel(CancelReasonPriceProtection)
+		} else if order.TimeInForce == TimeInForceIOC {
+			order.Cancel(CancelReasonIOCRemainder)
+		} else {
+			order.Cancel(CancelReasonInsufficientLiquidity)
 		}
-		for _, limit := range ob.Bids() {
-			limitMatches := limit.Fill(order)
-			if limit.TotalVolume == 0 {
-				ob.deleteLimit(BID_ORDER, limit)
+	}
+	ob.triggerStops()
+	ob.springBrackets()
+	ob.notifyBBO()
+
+	return matches, nil
+}
+
+// protectionPrice returns the worst price the market order may fill at, the tighter of its ProtectionPrice
+// and its MaxSlippagePct away from the current best opposite price, or zero if it's unbounded.
+func (ob *OrderBook) protectionPrice(order *Order) float64 {
+	protectionPrice := order.ProtectionPrice
+
+	best := ob.bestLimit(order.OrderPlacement.Opposite())
+	if order.MaxSlippagePct > 0 && best != nil {
+		var slippagePrice float64
+		if order.OrderPlacement == BID_ORDER {
+			slippagePrice = best.Price * (1 + order.MaxSlippagePct/100)
+			if protectionPrice == 0 || slippagePrice < protectionPrice {
+				protectionPrice = slippagePrice
 			}
-			matches = append(matches, limitMatches...)
-			if order.IsFilled() {
-				break
+		} else {
+			slippagePrice = best.Price * (1 - order.MaxSlippagePct/100)
+			if slippagePrice > protectionPrice {
+				protectionPrice = slippagePrice
 			}
 		}
 	}
 
-	return matches, nil
+	return ob.Config.RoundToTick(protectionPrice)
+}
+
+// sweep fills the order against the best opposite level until the order is filled, the side is empty,
+// the best level is worse than limitPrice or the market's max sweep depth is reached, which is reported
+// by depthReached. A zero limitPrice sweeps at any price.
+// The best level is looked up again on every iteration, so exhausted levels can be removed safely.
+// Nothing matches during an auction.
+func (ob *OrderBook) sweep(order *Order, limitPrice float64) (matches []Match, depthReached bool) {
+	if ob.auction {
+		return []Match{}, false
+	}
+	side := order.OrderPlacement.Opposite()
+	sweeping := ob.sweeping
+	ob.sweeping = true
+	defer func() { ob.sweeping = sweeping }()
+
+	matches = []Match{}
+	levels := 0
+	// Levels are visited through a cursor, as filling a level can remove it and, through OCO siblings, others
+	for limit := ob.bestLimit(side); !order.Status.IsTerminal(); limit = ob.levels(side).after(ob.Config.ToTicks(limit.Price)) {
+		if limit == nil || (limitPrice > 0 && !ob.crosses(order.OrderPlacement, limitPrice, limit.Price)) {
+			break
+		}
+		// Nothing's left to fill in executions of at least its MinFillSize
+		if order.MinFillSize > 0 && order.Size < order.MinFillSize {
+			break
+		}
+
+		if ob.Config.MaxSweepDepth > 0 && levels == ob.Config.MaxSweepDepth {
+			return matches, true
+		}
+		levels++
+
+		limitMatches := limit.Fill(order)
+		for i := range limitMatches {
+			ob.mutationSequence++
+			limitMatches[i].Sequence = ob.mutationSequence
+			ob.lastTradePrice = limitMatches[i].Price
+			ob.emitMatch(limitMatches[i])
+		}
+		matches = append(matches, limitMatches...)
+		if limit.IsEmpty() {
+			ob.deleteLimit(side, limit)
+		}
+	}
+
+	return matches, false
+}
+
+// crosses reports whether an order of the given placement limited to limitPrice can trade at price.
+// Prices are compared in ticks so float noise can't keep a level at the limit from matching.
+func (ob *OrderBook) crosses(placement OrderPlacement, limitPrice, price float64) bool {
+	if placement == BID_ORDER {
+		return ob.Config.ToTicks(price) <= ob.Config.ToTicks(limitPrice)
+	}
+	return ob.Config.ToTicks(price) >= ob.Config.ToTicks(limitPrice)
+}
+
+// bestLimit returns the best priced level of the given side, or nil if the side is empty.
+func (ob *OrderBook) bestLimit(side OrderPlacement) *Limit {
+	return ob.levelAt(side, 0)
+}
+
+// levelAt returns the level of the given side at depth i from the best price, or nil if the side isn't that deep.
+func (ob *OrderBook) levelAt(side OrderPlacement, i int) *Limit {
+	return ob.levels(side).at(i)
+}
+
+// levels returns the price levels of the given side.
+func (ob *OrderBook) levels(side OrderPlacement) *priceLevels {
+	if side == BID_ORDER {
+		return ob.bids
+	}
+	return ob.asks
+}
+
+// OrderCount returns the number of orders resting in the book.
+func (ob *OrderBook) OrderCount() int {
+	count := 0
+	for _, limit := range ob.asks.all() {
+		count += limit.Orders.Len()
+	}
+	for _, limit := range ob.bids.all() {
+		count += limit.Orders.Len()
+	}
+	return count
 }
 
 func (ob *OrderBook) AskTotalVolume() float64 {
 	totalVolume := 0.0
-	for _, ask := range ob.asks {
-		totalVolume += ask.TotalVolume
+	for _, ask := range ob.asks.all() {
+		totalVolume = addSize(totalVolume, ask.TotalVolume)
 	}
 
 	return totalVolume
@@ -247,96 +556,367 @@ func (ob *OrderBook) AskTotalVolume() float64 {
 
 func (ob *OrderBook) BidTotalVolume() float64 {
 	totalVolume := 0.0
-	for _, bid := range ob.bids {
-		totalVolume += bid.TotalVolume
+	for _, bid := range ob.bids.all() {
+		totalVolume = addSize(totalVolume, bid.TotalVolume)
 	}
 
 	return totalVolume
 }
 
-func (ob *OrderBook) PlaceLimitOrder(price float64, order *Order) error {
+// PlaceLimitOrder matches the order against the opposite side up to the given price and rests the remainder
+// at that price. An IOC order cancels the remainder instead, and so does any order whose remainder would
+// cross the book, having reached the market's max sweep depth or skipped executions below its MinFillSize.
+// Prices that aren't positive or off the tick size are rejected rather than rounded, see CheckOrder.
+func (ob *OrderBook) PlaceLimitOrder(price float64, order *Order) ([]Match, error) {
+	ob.accept(order)
+
+	if order.OrderPlacement != BID_ORDER && order.OrderPlacement != ASK_ORDER {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceLimitOrder: invalid order placement %q", order.OrderPlacement)
+	}
+	if price <= 0 {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceLimitOrder: invalid price %v", price)
+	}
+	if !order.TimeInForce.IsValid() {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceLimitOrder: invalid time in force %q", order.TimeInForce)
+	}
+	if !order.SelfTradePrevention.IsValid() {
+		return nil, reject(order, RejectReasonInvalidOrder, "PlaceLimitOrder: invalid self-trade prevention %q", order.SelfTradePrevention)
+	}
+	if err := ob.Config.CheckOrder(price, order.Size); err != nil {
+		order.Transition(OrderStatusRejected)
+		return nil, fmt.Errorf("PlaceLimitOrder: %w", err)
+	}
+	if err := ob.checkMinFillSize(order, ob.Config.RoundToTick(price)); err != nil {
+		return nil, err
+	}
+	if err := ob.checkTradingState(price); err != nil {
+		order.Transition(OrderStatusRejected)
+		return nil, fmt.Errorf("PlaceLimitOrder: %w", err)
+	}
+	if order.PostOnly {
+		if order.TimeInForce == TimeInForceIOC {
+			return nil, reject(order, RejectReasonInvalidOrder, "PlaceLimitOrder: post-only order can't be IOC")
+		}
+		if best := ob.bestLimit(order.OrderPlacement.Opposite()); best != nil && ob.crosses(order.OrderPlacement, price, best.Price) {
+			return nil, reject(order, RejectReasonPostOnlyWouldCross, "PlaceLimitOrder: post-only order at %.2f would cross %.2f", price, best.Price)
+		}
+	}
+
+	return ob.matchLimitOrder(price, order), nil
+}
+
+// matchLimitOrder matches the accepted limit order up to price and rests or cancels the remainder.
+func (ob *OrderBook) matchLimitOrder(price float64, order *Order) []Match {
+	matches, depthReached := ob.sweep(order, ob.Config.RoundToTick(price))
+	ob.restRemainder(price, order, depthReached)
+	ob.triggerStops()
+	ob.springBrackets()
+	ob.notifyBBO()
+
+	return matches
+}
+
+// restRemainder rests what's left of a limit order after its sweep, unless it's IOC or would cross the book
+// outside an auction.
+func (ob *OrderBook) restRemainder(price float64, order *Order, depthReached bool) {
+	if order.Status.IsTerminal() {
+		return
+	}
+
+	if order.TimeInForce == TimeInForceIOC {
+		order.Cancel(CancelReasonIOCRemainder)
+	} else if depthReached {
+		order.Cancel(CancelReasonMaxSweepDepth)
+	} else if best := ob.bestLimit(order.OrderPlacement.Opposite()); !ob.auction && best != nil && ob.crosses(order.OrderPlacement, price, best.Price) {
+		// Only orders it skipped for its MinFillSize are left in its way
+		order.Cancel(CancelReasonMinFillSize)
+	} else {
+		ob.restLimitOrder(price, order)
+	}
+}
+
+// restLimitOrder adds the order to its side's level at price, creating the level if needed.
+func (ob *OrderBook) restLimitOrder(price float64, order *Order) {
+	ticks := ob.Config.ToTicks(price)
 	var limit *Limit
 	if order.OrderPlacement == BID_ORDER {
-		limit = ob.BidLimits[price]
-	} else if order.OrderPlacement == ASK_ORDER {
-		limit = ob.AskLimits[price]
+		limit = ob.BidLimits[ticks]
 	} else {
-		return errors.New("invalid order placement")
+		limit = ob.AskLimits[ticks]
 	}
 
 	// Limit volume doesn't exist yet
 	if limit == nil {
-		limit = NewLimit(price)
+		limit = NewLimit(ob.Config.FromTicks(ticks))
+		ob.trackLevel(order.OrderPlacement, ticks, limit)
 		if order.OrderPlacement == BID_ORDER {
-			ob.bids = append(ob.bids, limit)
-			ob.BidLimits[price] = limit
+			ob.bids.insert(ticks, limit)
+			ob.BidLimits[ticks] = limit
 		} else {
-			ob.asks = append(ob.asks, limit)
-			ob.AskLimits[price] = limit
+			ob.asks.insert(ticks, limit)
+			ob.AskLimits[ticks] = limit
 		}
 	}
 
 	limit.AddOrder(order)
-	OrderIndex[order.ID] = OrderMetadata{
-		Order:  order,
-		Market: ob.Market,
+	ob.queueExpiry(order)
+}
+
+// restoreOrder rests an already accepted order without assigning it a new arrival sequence.
+func (ob *OrderBook) restoreOrder(price float64, order *Order) {
+	arrivalSequence := order.ArrivalSequence
+	ob.accept(order)
+	order.ArrivalSequence = arrivalSequence
+	ob.restLimitOrder(price, order)
+}
+
+// restoreStopOrder puts an already accepted stop order back in the off-book stops without triggering it.
+func (ob *OrderBook) restoreStopOrder(order *Order) {
+	arrivalSequence := order.ArrivalSequence
+	ob.accept(order)
+	order.ArrivalSequence = arrivalSequence
+	ob.stops.add(order)
+	ob.queueExpiry(order)
+}
+
+// Cancel cancels a resting order of this book at its owner's request.
+func (ob *OrderBook) Cancel(orderId int64) error {
+	order, exists := ob.orders[orderId]
+	if !exists {
+		return ErrNotFound
 	}
 
-	return nil
+	return ob.CancelOrderByID(orderId, order.OrderPlacement, CancelReasonUserRequested)
 }
 
-func (ob *OrderBook) CancelOrderByID(orderId int64, orderPlacement OrderPlacement) error {
-	var limits []*Limit
-	if orderPlacement == BID_ORDER {
-		limits = ob.bids
-	} else {
-		limits = ob.asks
+// CancelOrderByID removes a resting order in O(1) by following the book's order index straight to its node in the limit queue.
+func (ob *OrderBook) CancelOrderByID(orderId int64, orderPlacement OrderPlacement, reason CancelReason) error {
+	order, exists := ob.orders[orderId]
+	if !exists {
+		return ErrNotFound
 	}
 
-	for _, limit := range limits {
-		for _, order := range limit.Orders {
-			if order.ID == orderId {
-				limit.DeleteOrder(order)
-				delete(OrderIndex, order.ID)
-				if limit.TotalVolume == 0 {
-					ob.deleteLimit(orderPlacement, limit)
+	if order.OrderPlacement != orderPlacement {
+		return ErrNotFound
+	}
+	if order.StopPrice != 0 && order.Limit == nil {
+		return ob.cancelStopOrder(order, reason)
+	}
+
+	limit := order.Limit
+	if limit == nil {
+		return ErrNotFound
+	}
+
+	if err := order.Cancel(reason); err != nil {
+		return fmt.Errorf("CancelOrderByID: failed to cancel order %d: %w", orderId, err)
+	}
+	limit.DeleteOrder(order)
+	if limit.IsEmpty() {
+		ob.deleteLimit(orderPlacement, limit)
+	}
+	ob.springBrackets()
+	ob.notifyBBO()
+
+	return nil
+}
+
+// CancelAll cancels every resting and stop order of the owner in this book, or of everyone if owner is empty,
+// and returns the cancelled orders.
+func (ob *OrderBook) CancelAll(owner string, reason CancelReason) Orders {
+	orders := Orders{}
+	for _, limits := range [][]*Limit{ob.bids.all(), ob.asks.all()} {
+		for _, limit := range limits {
+			for _, order := range limit.Orders.All() {
+				if owner == "" || order.Owner == owner {
+					orders = append(orders, order)
 				}
-				return nil
 			}
 		}
 	}
+	for _, order := range ob.stops.all() {
+		if owner == "" || order.Owner == owner {
+			orders = append(orders, order)
+		}
+	}
+
+	cancelled := make(Orders, 0, len(orders))
+	for _, order := range orders {
+		// An OCO sibling may already be gone with its pair
+		if order.Status.IsTerminal() {
+			continue
+		}
+		if err := ob.CancelOrderByID(order.ID, order.OrderPlacement, reason); err == nil {
+			cancelled = append(cancelled, order)
+		}
+	}
 
-	return ErrNotFound
+	return cancelled
 }
 
-func (ob *OrderBook) deleteLimit(limitPlacement OrderPlacement, limit *Limit) {
-	if limitPlacement == BID_ORDER {
-		delete(ob.BidLimits, limit.Price)
+// ReplaceOrder atomically changes the price and/or remaining size of a resting limit order; zero keeps the
+// current value. Reducing only the size keeps the order's place in the queue. Any other change loses it.
+// Amends that keep the price or move it away from the touch can't cross, so the order is moved in place.
+// An amend improving the price matches first if it crosses, as a new order would. Replacements are fully
+// validated before the order is moved, so a rejected one leaves the order untouched. Changing the price of a pegged order unpegs it.
+func (ob *OrderBook) ReplaceOrder(orderId int64, price, size float64) ([]Match, error) {
+	order, exists := ob.orders[orderId]
+	if !exists || order.Limit == nil {
+		return nil, ErrNotFound
+	}
 
-		for i := 0; i < len(ob.bids); i++ {
-			if ob.bids[i] == limit {
-				ob.bids[i] = ob.bids[len(ob.bids)-1]
-				ob.bids = ob.bids[:len(ob.bids)-1]
-			}
+	limit := order.Limit
+	if price == 0 {
+		price = limit.Price
+	}
+	if size == 0 {
+		size = order.Size
+	}
+	size = RoundSize(size)
+	if price < 0 || size < 0 {
+		return nil, &RejectError{Reason: RejectReasonInvalidOrder, msg: fmt.Sprintf("ReplaceOrder: invalid price %.2f or size %.2f", price, size)}
+	}
+	if err := ob.Config.CheckOrder(price, size); err != nil {
+		return nil, fmt.Errorf("ReplaceOrder: %w", err)
+	}
+
+	samePrice := ob.Config.ToTicks(price) == ob.Config.ToTicks(limit.Price)
+	if samePrice && size <= order.Size {
+		ob.mutationSequence++
+		limit.TotalVolume = subSize(limit.TotalVolume, subSize(order.Size, size))
+		order.Size = size
+		limit.changed()
+		ob.notifyBBO()
+		return []Match{}, nil
+	}
+
+	improves := !samePrice && ob.crosses(order.OrderPlacement, price, limit.Price)
+	if improves && order.PostOnly {
+		if best := ob.bestLimit(order.OrderPlacement.Opposite()); best != nil && ob.crosses(order.OrderPlacement, price, best.Price) {
+			return nil, &RejectError{Reason: RejectReasonPostOnlyWouldCross, msg: fmt.Sprintf("ReplaceOrder: post-only order at %.2f would cross %.2f", price, best.Price)}
 		}
-	} else {
-		delete(ob.AskLimits, limit.Price)
+	}
+	if improves {
+		if err := ob.minFillSizeError(order, size, ob.Config.RoundToTick(price)); err != nil {
+			return nil, fmt.Errorf("ReplaceOrder: %w", err)
+		}
+	}
 
-		for i := 0; i < len(ob.asks); i++ {
-			if ob.asks[i] == limit {
-				ob.asks[i] = ob.asks[len(ob.asks)-1]
-				ob.asks = ob.asks[:len(ob.asks)-1]
-			}
+	if !samePrice {
+		if err := ob.checkTradingState(price); err != nil {
+			return nil, fmt.Errorf("ReplaceOrder: %w", err)
 		}
 	}
+
+	limit.DeleteOrder(order)
+	if limit.IsEmpty() {
+		ob.deleteLimit(order.OrderPlacement, limit)
+	}
+	order.Size = size
+	if !samePrice {
+		order.Pegged = false
+	}
+
+	// Back of the queue at the new level, once it matched what it crosses if the price improves
+	ob.mutationSequence++
+	ob.arrivalSequence++
+	order.ArrivalSequence = ob.arrivalSequence
+	if improves {
+		return ob.matchLimitOrder(price, order), nil
+	}
+	ob.restLimitOrder(price, order)
+	ob.notifyBBO()
+	return []Match{}, nil
+}
+
+// newOrder creates an order of the book's own, taking its ID and timestamp from the book.
+func (ob *OrderBook) newOrder(orderPlacement OrderPlacement, size float64) *Order {
+	id := int64(0)
+	if ob.NextOrderID != nil {
+		id = ob.NextOrderID()
+	} else {
+		id = NextOrderID()
+	}
+	return newOrder(id, orderPlacement, size, ob.now())
+}
+
+func (ob *OrderBook) now() time.Time {
+	if ob.Clock != nil {
+		return ob.Clock()
+	}
+	return time.Now()
+}
+
+// accept stamps the order's arrival sequence, indexes it and hooks its status transitions into the book's listener.
+// A good-till-date order is only queued for expiry once it rests.
+func (ob *OrderBook) accept(order *Order) {
+	ob.mutationSequence++
+	ob.arrivalSequence++
+	order.ArrivalSequence = ob.arrivalSequence
+	order.onTransition = ob.emitTransition
+	ob.orders[order.ID] = order
 }
 
+// MutationSequence returns the sequence number of the book's latest mutation. Every place, amend, fill and
+// status change increments it, so it orders API responses against the matches and transitions emitted.
+func (ob *OrderBook) MutationSequence() int64 {
+	return ob.mutationSequence
+}
+
+func (ob *OrderBook) emitTransition(transition OrderTransition) {
+	ob.mutationSequence++
+	transition.Sequence = ob.mutationSequence
+
+	if transition.To.IsTerminal() {
+		delete(ob.orders, transition.Order.ID)
+		ob.unqueueExpiry(transition.Order)
+	}
+
+	if ob.OnTransition != nil {
+		ob.OnTransition(transition)
+	}
+
+	if order := transition.Order; transition.To.IsTerminal() && order.Bracket != nil && order.FilledSize > 0 {
+		ob.pendingBrackets = append(ob.pendingBrackets, order)
+	}
+
+	ob.cancelSibling(transition.Order)
+}
+
+func (ob *OrderBook) emitMatch(match Match) {
+	if ob.OnMatch != nil {
+		ob.OnMatch(match)
+	}
+}
+
+func (ob *OrderBook) deleteLimit(limitPlacement OrderPlacement, limit *Limit) {
+	ticks := ob.Config.ToTicks(limit.Price)
+	if limitPlacement == BID_ORDER {
+		delete(ob.BidLimits, ticks)
+		ob.bids.remove(ticks)
+	} else {
+		delete(ob.AskLimits, ticks)
+		ob.asks.remove(ticks)
+	}
+}
+
+// Asks returns the ask levels, lowest price first.
 func (ob *OrderBook) Asks() []*Limit {
-	sort.Sort(ByBestAsk{ob.asks})
-	return ob.asks
+	return ob.asks.all()
 }
 
+// Bids returns the bid levels, highest price first.
 func (ob *OrderBook) Bids() []*Limit {
-	sort.Sort(ByBestBid{ob.bids})
-	return ob.bids
+	return ob.bids.all()
+}
+
+// BestBid returns the highest bid level, or nil if there are no bids. Unlike Bids()[0] it's O(1): the level
+// list keeps its best level first as levels are added and removed.
+func (ob *OrderBook) BestBid() *Limit {
+	return ob.bestLimit(BID_ORDER)
+}
+
+// BestAsk returns the lowest ask level, or nil if there are no asks. It's O(1) like BestBid.
+func (ob *OrderBook) BestAsk() *Limit {
+	return ob.bestLimit(ASK_ORDER)
 }
@@ -2,6 +2,7 @@ package entity_test
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/idzharbae/crypto-exchange/src/internal/entity"
@@ -51,6 +52,10 @@ func TestPlaceMarketOrder(t *testing.T) {
 
 			So(err, ShouldNotBeNil)
 			So(err.Error(), ShouldContainSubstring, "PlaceMarketOrder: not enough ask volume in the market. asks: 200.00, bids: 500.00")
+
+			reason, rejected := entity.RejectReasonOf(err)
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonInsufficientLiquidity)
 		})
 
 		Convey("Should return error if not enough volume (ask)", func() {
@@ -67,6 +72,26 @@ func TestPlaceMarketOrder(t *testing.T) {
 			So(err.Error(), ShouldContainSubstring, "PlaceMarketOrder: not enough bid volume in the market. asks: 500.00, bids: 200.00")
 		})
 
+		Convey("Should fill available volume and cancel the rest if partial fill is allowed", func() {
+			ob := entity.NewOrderBook("test")
+			sellOrder := entity.NewOrder(entity.ASK_ORDER, 100)
+			ob.PlaceLimitOrder(10_000, sellOrder)
+			sellOrder2 := entity.NewOrder(entity.ASK_ORDER, 100)
+			ob.PlaceLimitOrder(12_000, sellOrder2)
+
+			buyOrder := entity.NewOrder(entity.BID_ORDER, 500)
+			buyOrder.AllowPartialFill = true
+			matches, err := ob.PlaceMarketOrder(buyOrder)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+			So(buyOrder.FilledSize, ShouldEqual, 200)
+			So(buyOrder.Size, ShouldEqual, 300)
+			So(buyOrder.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(buyOrder.CancelReason, ShouldEqual, entity.CancelReasonInsufficientLiquidity)
+			So(len(ob.Asks()), ShouldEqual, 0)
+		})
+
 		Convey("Should return matches if volume is enough", func() {
 			ob := entity.NewOrderBook("test")
 			sellOrder := entity.NewOrder(entity.ASK_ORDER, 100)
@@ -120,5 +145,987 @@ func TestPlaceMarketOrder(t *testing.T) {
 			So(buyOrder4.Size, ShouldEqual, 1)
 			So(len(ob.Bids()), ShouldEqual, 2)
 		})
+
+		Convey("Should reject a non-positive size without touching the book", func() {
+			ob := entity.NewOrderBook("test")
+			sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+			ob.PlaceLimitOrder(100, sellOrder)
+
+			for _, size := range []float64{0, -3} {
+				buyOrder := entity.NewOrder(entity.BID_ORDER, size)
+				matches, err := ob.PlaceMarketOrder(buyOrder)
+
+				So(matches, ShouldBeEmpty)
+				reason, rejected := entity.RejectReasonOf(err)
+				So(rejected, ShouldBeTrue)
+				So(reason, ShouldEqual, entity.RejectReasonSizeTooSmall)
+				So(buyOrder.Status, ShouldEqual, entity.OrderStatusRejected)
+			}
+
+			buyOrder := entity.NewOrder(entity.BID_ORDER, -1)
+			_, err := ob.PlaceLimitOrder(100, buyOrder)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonSizeTooSmall)
+
+			So(sellOrder.Size, ShouldEqual, 10)
+			So(sellOrder.Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.AskTotalVolume(), ShouldEqual, 10)
+			So(ob.LastTradePrice(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestLimitTimePriority(t *testing.T) {
+	Convey("When filling a limit after interleaved adds, cancels and fills", t, func() {
+		ob := entity.NewOrderBook("test")
+		sellOrders := []*entity.Order{}
+		for i := 0; i < 5; i++ {
+			sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+			ob.PlaceLimitOrder(10_000, sellOrder)
+			sellOrders = append(sellOrders, sellOrder)
+		}
+
+		// Cancel from the middle, partially fill the front, then add more to the back
+		ob.CancelOrderByID(sellOrders[1].ID, entity.ASK_ORDER, entity.CancelReasonUserRequested)
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 4))
+		ob.CancelOrderByID(sellOrders[3].ID, entity.ASK_ORDER, entity.CancelReasonUserRequested)
+		lateSellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_000, lateSellOrder)
+
+		Convey("Should keep resting orders in arrival order", func() {
+			So(ob.Asks()[0].Orders.All(), ShouldResemble, entity.Orders{sellOrders[0], sellOrders[2], sellOrders[4], lateSellOrder})
+		})
+
+		Convey("Should fill strictly oldest first", func() {
+			matches, err := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 30))
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 4)
+			So(matches[0].Ask, ShouldEqual, sellOrders[0])
+			So(matches[0].SizeFilled, ShouldEqual, 6)
+			So(matches[1].Ask, ShouldEqual, sellOrders[2])
+			So(matches[2].Ask, ShouldEqual, sellOrders[4])
+			So(matches[3].Ask, ShouldEqual, lateSellOrder)
+			So(matches[3].SizeFilled, ShouldEqual, 4)
+			So(ob.Asks()[0].Orders.All(), ShouldResemble, entity.Orders{lateSellOrder})
+		})
+	})
+}
+
+func BenchmarkCancelOrderByID(b *testing.B) {
+	const levels, ordersPerLevel = 1_000, 10
+
+	ob := entity.NewOrderBook("bench")
+	for i := 0; i < levels; i++ {
+		for j := 0; j < ordersPerLevel; j++ {
+			ob.PlaceLimitOrder(float64(10_000+i), entity.NewOrder(entity.ASK_ORDER, 1))
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Cancel the newest order at the deepest level and put a fresh one back to keep the book depth constant
+		b.StopTimer()
+		order := entity.NewOrder(entity.ASK_ORDER, 1)
+		ob.PlaceLimitOrder(float64(10_000+levels-1), order)
+		b.StartTimer()
+
+		ob.CancelOrderByID(order.ID, entity.ASK_ORDER, entity.CancelReasonUserRequested)
+	}
+}
+
+func TestLevelOrdering(t *testing.T) {
+	Convey("When levels are added and removed out of price order", t, func() {
+		ob := entity.NewOrderBook("test")
+		orders := map[float64]*entity.Order{}
+		for i := 0; i < 200; i++ {
+			price := float64((i*37)%200 + 1)
+			orders[price] = entity.NewOrder(entity.BID_ORDER, 1)
+			ob.PlaceLimitOrder(price, orders[price])
+			ob.PlaceLimitOrder(price+1_000, entity.NewOrder(entity.ASK_ORDER, 1))
+		}
+		for price := 2.0; price <= 200; price += 2 {
+			So(ob.Cancel(orders[price].ID), ShouldBeNil)
+		}
+
+		Convey("Should keep each side ordered from the best price", func() {
+			bids, asks := ob.Bids(), ob.Asks()
+			So(bids, ShouldHaveLength, 100)
+			So(asks, ShouldHaveLength, 200)
+			for i := 1; i < len(bids); i++ {
+				So(bids[i].Price, ShouldEqual, bids[i-1].Price-2)
+			}
+			for i := 1; i < len(asks); i++ {
+				So(asks[i].Price, ShouldEqual, asks[i-1].Price+1)
+			}
+			So(bids[0].Price, ShouldEqual, 199)
+			So(asks[0].Price, ShouldEqual, 1_001)
+		})
+	})
+}
+
+func TestPriceTicks(t *testing.T) {
+	Convey("When placing limit orders at prices that differ below the tick size", t, func() {
+		ob := entity.NewOrderBookWithConfig("test", entity.MarketConfig{TickSize: 0.01})
+		ob.PlaceLimitOrder(10_000, entity.NewOrder(entity.BID_ORDER, 1))
+		ob.PlaceLimitOrder(10_000.000000001, entity.NewOrder(entity.BID_ORDER, 1))
+		ob.PlaceLimitOrder(0.1+0.2, entity.NewOrder(entity.BID_ORDER, 1))
+
+		Convey("Should share the same price level", func() {
+			So(len(ob.Bids()), ShouldEqual, 2)
+			So(ob.Bids()[0].Price, ShouldEqual, 10_000)
+			So(ob.Bids()[0].TotalVolume, ShouldEqual, 2)
+		})
+
+		Convey("Should format level prices back to tick precision", func() {
+			So(ob.Bids()[1].Price, ShouldEqual, 0.3)
+		})
+	})
+}
+
+func TestSizePrecision(t *testing.T) {
+	Convey("When sizes don't add up exactly in binary floating point", t, func() {
+		ob := entity.NewOrderBook("test")
+		first := entity.NewOrder(entity.ASK_ORDER, 0.1)
+		ob.PlaceLimitOrder(100, first)
+		second := entity.NewOrder(entity.ASK_ORDER, 0.2)
+		ob.PlaceLimitOrder(100, second)
+
+		Convey("Should keep the level volume exact", func() {
+			So(ob.AskTotalVolume(), ShouldEqual, 0.3)
+		})
+
+		Convey("Should fill every order completely without leaving dust behind", func() {
+			buyOrder := entity.NewOrder(entity.BID_ORDER, 0.3)
+			_, err := ob.PlaceMarketOrder(buyOrder)
+
+			So(err, ShouldBeNil)
+			So(buyOrder.FilledSize, ShouldEqual, 0.3)
+			So(first.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(second.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(second.Size, ShouldEqual, 0)
+			So(ob.Asks(), ShouldBeEmpty)
+		})
+
+		Convey("Should round sizes to the size precision", func() {
+			So(entity.NewOrder(entity.BID_ORDER, 0.123456789).Size, ShouldEqual, 0.12345679)
+		})
+	})
+}
+
+func TestOrderIncrements(t *testing.T) {
+	Convey("When a market has a tick size, lot size and minimum notional", t, func() {
+		ob := entity.NewOrderBookWithConfig("test", entity.MarketConfig{TickSize: 0.01, LotSize: 0.001, MinNotional: 10})
+
+		place := func(price, size float64) (*entity.Order, error) {
+			order := entity.NewOrder(entity.BID_ORDER, size)
+			_, err := ob.PlaceLimitOrder(price, order)
+			return order, err
+		}
+
+		Convey("Should reject prices between ticks", func() {
+			order, err := place(100.005, 1)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidTickSize)
+			So(order.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Should reject sizes between lots", func() {
+			_, err := place(100, 1.0005)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidLotSize)
+		})
+
+		Convey("Should reject orders worth less than the minimum notional", func() {
+			_, err := place(100, 0.099)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonBelowMinNotional)
+		})
+
+		Convey("Should accept aligned orders despite floating point noise", func() {
+			_, err := place(0.1+0.2, 100.1)
+			So(err, ShouldBeNil)
+			_, err = place(100, 0.1+0.2)
+			So(err, ShouldBeNil)
+			So(ob.Bids(), ShouldHaveLength, 2)
+		})
+
+		Convey("Should reject replacing an order to a misaligned price or size", func() {
+			order, _ := place(100, 1)
+			_, err := ob.ReplaceOrder(order.ID, 100.001, 0)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidTickSize)
+			_, err = ob.ReplaceOrder(order.ID, 0, 0.0001)
+			reason, _ = entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidLotSize)
+			So(order.Limit.Price, ShouldEqual, 100)
+			So(order.Size, ShouldEqual, 1)
+		})
+
+		Convey("Should reject limit orders without a positive price", func() {
+			bid, _ := place(100, 1)
+			ask := entity.NewOrder(entity.ASK_ORDER, 1)
+			_, err := ob.PlaceLimitOrder(0, ask)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(ask.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(bid.Size, ShouldEqual, 1)
+			So(ob.Asks(), ShouldBeEmpty)
+
+			So(ob.Config.CheckOrder(-100, 1), ShouldNotBeNil)
+			So(ob.Config.CheckOrder(100, 0), ShouldNotBeNil)
+		})
+
+		Convey("Should check market and stop orders against the lot size", func() {
+			market := entity.NewOrder(entity.BID_ORDER, 1.0005)
+			_, err := ob.PlaceMarketOrder(market)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidLotSize)
+
+			stop := entity.NewOrder(entity.BID_ORDER, 1.0005)
+			err = ob.PlaceStopOrder(110, stop)
+			reason, _ = entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidLotSize)
+
+			stopLimit := entity.NewOrder(entity.BID_ORDER, 1)
+			err = ob.PlaceStopLimitOrder(110, 110.005, stopLimit)
+			reason, _ = entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidTickSize)
+			So(stopLimit.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(ob.StopOrders(), ShouldBeEmpty)
+		})
+
+		Convey("Should round sizes down to whole lots", func() {
+			So(ob.Config.RoundToLot(1.0009), ShouldEqual, 1)
+			So(ob.Config.RoundToLot(0.1+0.2), ShouldEqual, 0.3)
+		})
+	})
+}
+
+func TestMarketOrderSweep(t *testing.T) {
+	Convey("When a market order sweeps multiple levels", t, func() {
+		ob := entity.NewOrderBook("test")
+		sellOrders := []*entity.Order{}
+		for i := 0; i < 4; i++ {
+			sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+			ob.PlaceLimitOrder(float64(10_000+i*100), sellOrder)
+			sellOrders = append(sellOrders, sellOrder)
+		}
+
+		Convey("Should exhaust levels in price order and keep the rest", func() {
+			matches, err := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 25))
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 3)
+			So(matches[0].Price, ShouldEqual, 10_000)
+			So(matches[1].Price, ShouldEqual, 10_100)
+			So(matches[2].Price, ShouldEqual, 10_200)
+			So(matches[2].SizeFilled, ShouldEqual, 5)
+			So(len(ob.Asks()), ShouldEqual, 2)
+			So(ob.Asks()[0].Price, ShouldEqual, 10_200)
+			So(ob.AskLimits, ShouldNotContainKey, int64(1_000_000))
+		})
+
+		Convey("Should exhaust every level including the last one", func() {
+			matches, err := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 40))
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 4)
+			So(matches[3].Ask, ShouldEqual, sellOrders[3])
+			So(len(ob.Asks()), ShouldEqual, 0)
+			So(len(ob.AskLimits), ShouldEqual, 0)
+			So(ob.AskTotalVolume(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestArrivalSequence(t *testing.T) {
+	Convey("When orders share the same timestamp", t, func() {
+		ob := entity.NewOrderBook("test")
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		sellOrder2 := entity.NewOrder(entity.ASK_ORDER, 10)
+		sellOrder2.Timestamp = sellOrder.Timestamp
+		ob.PlaceLimitOrder(10_000, sellOrder)
+		ob.PlaceLimitOrder(10_000, sellOrder2)
+
+		Convey("Should assign increasing arrival sequences", func() {
+			So(sellOrder2.ArrivalSequence, ShouldBeGreaterThan, sellOrder.ArrivalSequence)
+		})
+
+		Convey("Should fill the first accepted order first", func() {
+			matches, err := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 10))
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 1)
+			So(matches[0].Ask, ShouldEqual, sellOrder)
+		})
+	})
+
+	Convey("When pushing an order with an earlier arrival sequence", t, func() {
+		l := entity.NewLimit(10_000)
+		late := &entity.Order{ArrivalSequence: 2}
+		early := &entity.Order{ArrivalSequence: 1}
+		l.AddOrder(late)
+		l.AddOrder(early)
+
+		Convey("Should queue it ahead of later arrivals", func() {
+			So(l.Orders.All(), ShouldResemble, entity.Orders{early, late})
+		})
+	})
+}
+
+func TestOrderBookAPI(t *testing.T) {
+	Convey("When using the book through Place, Cancel and Snapshot", t, func() {
+		ob := entity.NewOrderBook("test")
+		matched := []entity.Match{}
+		ob.OnMatch = func(match entity.Match) {
+			matched = append(matched, match)
+		}
+
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		_, err := ob.Place(entity.LimitOrder, 10_000, sellOrder)
+		So(err, ShouldBeNil)
+		sellOrder2 := entity.NewOrder(entity.ASK_ORDER, 10)
+		_, err = ob.Place(entity.LimitOrder, 10_100, sellOrder2)
+		So(err, ShouldBeNil)
+
+		Convey("Should call OnMatch for every executed match", func() {
+			matches, err := ob.Place(entity.MarketOrder, 0, entity.NewOrder(entity.BID_ORDER, 15))
+
+			So(err, ShouldBeNil)
+			So(matched, ShouldResemble, matches)
+			So(len(matched), ShouldEqual, 2)
+		})
+
+		Convey("Should cancel by ID alone", func() {
+			So(ob.Cancel(sellOrder.ID), ShouldBeNil)
+			So(ob.Cancel(sellOrder.ID), ShouldEqual, entity.ErrNotFound)
+			So(len(ob.Asks()), ShouldEqual, 1)
+		})
+
+		Convey("Should snapshot levels best first without sharing state", func() {
+			snapshot := ob.Snapshot()
+			sellOrder.Size = 1
+
+			So(snapshot.Market, ShouldEqual, "test")
+			So(len(snapshot.Asks), ShouldEqual, 2)
+			So(snapshot.Asks[0].Price, ShouldEqual, 10_000)
+			So(snapshot.Asks[0].Orders[0].ID, ShouldEqual, sellOrder.ID)
+			So(snapshot.Asks[0].Orders[0].Size, ShouldEqual, 10)
+			So(len(snapshot.Bids), ShouldEqual, 0)
+		})
+
+		Convey("Should track the best bid and ask as levels come and go", func() {
+			So(ob.BestBid(), ShouldBeNil)
+			So(ob.BestAsk().Price, ShouldEqual, 10_000)
+
+			bid := entity.NewOrder(entity.BID_ORDER, 1)
+			ob.Place(entity.LimitOrder, 9_900, bid)
+			ob.Place(entity.LimitOrder, 9_800, entity.NewOrder(entity.BID_ORDER, 1))
+			So(ob.BestBid(), ShouldEqual, ob.Bids()[0])
+			So(ob.BestBid().Price, ShouldEqual, 9_900)
+
+			ob.Cancel(bid.ID)
+			ob.Cancel(sellOrder.ID)
+			So(ob.BestBid().Price, ShouldEqual, 9_800)
+			So(ob.BestAsk().Price, ShouldEqual, 10_100)
+		})
+
+		Convey("Should reject unknown order types", func() {
+			_, err := ob.Place("STOP", 0, entity.NewOrder(entity.BID_ORDER, 1))
+
+			reason, rejected := entity.RejectReasonOf(err)
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+		})
+	})
+}
+
+func TestMutationSequence(t *testing.T) {
+	Convey("When mutating a book", t, func() {
+		ob := entity.NewOrderBook("test")
+		transitions := []entity.OrderTransition{}
+		ob.OnTransition = func(transition entity.OrderTransition) {
+			transitions = append(transitions, transition)
+		}
+
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 5)
+		ob.PlaceLimitOrder(100, sellOrder)
+		placed := ob.MutationSequence()
+		ob.ReplaceOrder(sellOrder.ID, 0, 4)
+		amended := ob.MutationSequence()
+		matches, _ := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+		filled := ob.MutationSequence()
+		ob.Cancel(sellOrder.ID)
+
+		Convey("Should number every place, amend, fill and status change in order", func() {
+			So(placed, ShouldEqual, 1)
+			So(amended, ShouldEqual, placed+1)
+			So(filled, ShouldBeGreaterThan, amended)
+			So(ob.MutationSequence(), ShouldBeGreaterThan, filled)
+
+			So(matches[0].Sequence, ShouldBeGreaterThan, amended)
+			last := amended
+			for _, transition := range transitions {
+				So(transition.Sequence, ShouldBeGreaterThan, last)
+				last = transition.Sequence
+			}
+			So(last, ShouldEqual, ob.MutationSequence())
+		})
+
+		Convey("Should carry on from a restored snapshot", func() {
+			restored := entity.RestoreOrderBook(ob.Snapshot(), entity.DefaultMarketConfig)
+			So(restored.MutationSequence(), ShouldEqual, ob.MutationSequence())
+		})
+	})
+}
+
+func TestOrderIndexLifecycle(t *testing.T) {
+	Convey("When orders go through their lifecycle", t, func() {
+		ob := entity.NewOrderBook("test")
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_000, sellOrder)
+		sellOrder2 := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_000, sellOrder2)
+
+		Convey("Should index resting orders on accept", func() {
+			indexed, exists := ob.LookupOrder(sellOrder.ID)
+			So(exists, ShouldBeTrue)
+			So(indexed, ShouldEqual, sellOrder)
+		})
+
+		Convey("Should not see the orders of other books", func() {
+			_, exists := entity.NewOrderBook("other").LookupOrder(sellOrder.ID)
+			So(exists, ShouldBeFalse)
+			So(entity.NewOrderBook("test").Cancel(sellOrder.ID), ShouldEqual, entity.ErrNotFound)
+		})
+
+		Convey("Should remove filled orders, including the market order", func() {
+			buyOrder := entity.NewOrder(entity.BID_ORDER, 15)
+			ob.PlaceMarketOrder(buyOrder)
+
+			_, exists := ob.LookupOrder(sellOrder.ID)
+			So(exists, ShouldBeFalse)
+			_, exists = ob.LookupOrder(buyOrder.ID)
+			So(exists, ShouldBeFalse)
+			_, exists = ob.LookupOrder(sellOrder2.ID)
+			So(exists, ShouldBeTrue)
+		})
+
+		Convey("Should remove rejected and cancelled orders", func() {
+			buyOrder := entity.NewOrder(entity.BID_ORDER, 100)
+			ob.PlaceMarketOrder(buyOrder)
+			ob.Cancel(sellOrder2.ID)
+
+			_, exists := ob.LookupOrder(buyOrder.ID)
+			So(exists, ShouldBeFalse)
+			_, exists = ob.LookupOrder(sellOrder2.ID)
+			So(exists, ShouldBeFalse)
+			So(ob.OrderIndexSize(), ShouldEqual, 1)
+		})
+
+		Reset(func() {
+			ob.Cancel(sellOrder.ID)
+			ob.Cancel(sellOrder2.ID)
+		})
+	})
+
+	Convey("When every order of a book is gone", t, func() {
+		ob := entity.NewOrderBook("test")
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_000, sellOrder)
+		sellOrder2 := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_100, sellOrder2)
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 10))
+		ob.Cancel(sellOrder2.ID)
+
+		Convey("Should not leave entries behind in the index", func() {
+			So(ob.OrderCount(), ShouldEqual, 0)
+			So(ob.OrderIndexSize(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestMaxSweepDepth(t *testing.T) {
+	Convey("When a market order would sweep past the max sweep depth", t, func() {
+		ob := entity.NewOrderBookWithConfig("test", entity.MarketConfig{TickSize: 0.01, MaxSweepDepth: 2})
+		for i := 0; i < 4; i++ {
+			ob.PlaceLimitOrder(float64(10_000+i*100), entity.NewOrder(entity.ASK_ORDER, 10))
+		}
+
+		buyOrder := entity.NewOrder(entity.BID_ORDER, 35)
+		matches, err := ob.PlaceMarketOrder(buyOrder)
+
+		Convey("Should stop at the cap and cancel the remainder", func() {
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+			So(buyOrder.FilledSize, ShouldEqual, 20)
+			So(buyOrder.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(buyOrder.CancelReason, ShouldEqual, entity.CancelReasonMaxSweepDepth)
+			So(len(ob.Asks()), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestPostOnly(t *testing.T) {
+	Convey("When placing post-only limit orders", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(98, entity.NewOrder(entity.BID_ORDER, 5))
+
+		Convey("Should rest when it doesn't cross the spread", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 1)
+			order.PostOnly = true
+
+			_, err := ob.PlaceLimitOrder(99.99, order)
+
+			So(err, ShouldBeNil)
+			So(order.Limit.Price, ShouldEqual, 99.99)
+		})
+
+		Convey("Should reject a bid at or above the best ask", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 1)
+			order.PostOnly = true
+
+			_, err := ob.PlaceLimitOrder(100, order)
+
+			reason, rejected := entity.RejectReasonOf(err)
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonPostOnlyWouldCross)
+			So(order.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(ob.BidTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("Should reject an ask at or below the best bid", func() {
+			order := entity.NewOrder(entity.ASK_ORDER, 1)
+			order.PostOnly = true
+
+			_, err := ob.PlaceLimitOrder(97, order)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonPostOnlyWouldCross)
+			So(ob.AskTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("Should reject post-only market and IOC orders", func() {
+			market := entity.NewOrder(entity.BID_ORDER, 1)
+			market.PostOnly = true
+			_, err := ob.PlaceMarketOrder(market)
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+
+			ioc := entity.NewOrder(entity.BID_ORDER, 1)
+			ioc.PostOnly = true
+			ioc.TimeInForce = entity.TimeInForceIOC
+			_, err = ob.PlaceLimitOrder(90, ioc)
+			reason, _ = entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+		})
+	})
+}
+
+func TestAggressiveLimitOrder(t *testing.T) {
+	Convey("When a limit order crosses the book", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(103, entity.NewOrder(entity.ASK_ORDER, 5))
+
+		Convey("Should match up to its limit price and rest the residual", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 12)
+
+			matches, err := ob.PlaceLimitOrder(102, order)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+			So(matches[0].Price, ShouldEqual, 100)
+			So(matches[1].Price, ShouldEqual, 101)
+			So(order.Status, ShouldEqual, entity.OrderStatusPartiallyFilled)
+			So(order.Limit.Price, ShouldEqual, 102)
+			So(ob.BidTotalVolume(), ShouldEqual, 2)
+			So(ob.Asks()[0].Price, ShouldEqual, 103)
+			So(ob.LastTradePrice(), ShouldEqual, 101)
+		})
+
+		Convey("Should not rest when fully filled", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 5)
+
+			ob.PlaceLimitOrder(100, order)
+
+			So(order.IsFilled(), ShouldBeTrue)
+			So(order.Limit, ShouldBeNil)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Should never leave the book crossed", func() {
+			ob.PlaceLimitOrder(110, entity.NewOrder(entity.BID_ORDER, 20))
+
+			So(ob.Asks(), ShouldBeEmpty)
+			So(ob.Bids()[0].TotalVolume, ShouldEqual, 5)
+		})
+
+		Convey("Should cancel the residual instead of resting crossed at the max sweep depth", func() {
+			ob.Config.MaxSweepDepth = 1
+			order := entity.NewOrder(entity.BID_ORDER, 12)
+
+			ob.PlaceLimitOrder(102, order)
+
+			So(order.FilledSize, ShouldEqual, 5)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonMaxSweepDepth)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestMarketOrderPriceProtection(t *testing.T) {
+	Convey("When placing protected market orders", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(105, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(95, entity.NewOrder(entity.BID_ORDER, 5))
+		ob.PlaceLimitOrder(90, entity.NewOrder(entity.BID_ORDER, 5))
+
+		Convey("Should stop at the protection price and cancel the remainder", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 15)
+			order.ProtectionPrice = 101
+
+			matches, err := ob.PlaceMarketOrder(order)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+			So(order.FilledSize, ShouldEqual, 10)
+			So(order.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonPriceProtection)
+			So(ob.AskTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("Should bound a buy by max slippage above the best ask", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 15)
+			order.MaxSlippagePct = 2
+
+			ob.PlaceMarketOrder(order)
+
+			So(order.FilledSize, ShouldEqual, 10)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonPriceProtection)
+		})
+
+		Convey("Should bound a sell by max slippage below the best bid", func() {
+			order := entity.NewOrder(entity.ASK_ORDER, 10)
+			order.MaxSlippagePct = 5
+
+			ob.PlaceMarketOrder(order)
+
+			So(order.FilledSize, ShouldEqual, 5)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonPriceProtection)
+			So(ob.BidTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("Should use the tighter of both bounds", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 15)
+			order.MaxSlippagePct = 10
+			order.ProtectionPrice = 100
+
+			ob.PlaceMarketOrder(order)
+
+			So(order.FilledSize, ShouldEqual, 5)
+		})
+
+		Convey("Should fill completely when the bound isn't reached", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 15)
+			order.MaxSlippagePct = 10
+
+			ob.PlaceMarketOrder(order)
+
+			So(order.IsFilled(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestReplaceOrder(t *testing.T) {
+	Convey("When replacing a resting order", t, func() {
+		ob := entity.NewOrderBook("test")
+		first := entity.NewOrder(entity.ASK_ORDER, 5)
+		ob.PlaceLimitOrder(100, first)
+		second := entity.NewOrder(entity.ASK_ORDER, 5)
+		ob.PlaceLimitOrder(100, second)
+		ob.PlaceLimitOrder(98, entity.NewOrder(entity.BID_ORDER, 5))
+
+		Convey("Should keep queue priority when only reducing size", func() {
+			matches, err := ob.ReplaceOrder(first.ID, 0, 2)
+
+			So(err, ShouldBeNil)
+			So(matches, ShouldBeEmpty)
+			So(first.Size, ShouldEqual, 2)
+			So(ob.Asks()[0].Orders.All(), ShouldResemble, entity.Orders{first, second})
+			So(ob.Asks()[0].TotalVolume, ShouldEqual, 7)
+		})
+
+		Convey("Should lose priority when increasing size", func() {
+			ob.ReplaceOrder(first.ID, 100, 8)
+
+			So(ob.Asks()[0].Orders.All(), ShouldResemble, entity.Orders{second, first})
+			So(ob.Asks()[0].TotalVolume, ShouldEqual, 13)
+		})
+
+		Convey("Should move the order to its new price level", func() {
+			ob.ReplaceOrder(first.ID, 101, 0)
+
+			So(len(ob.Asks()), ShouldEqual, 2)
+			So(ob.Asks()[1].Price, ShouldEqual, 101)
+			So(first.Limit, ShouldEqual, ob.Asks()[1])
+			So(ob.AskTotalVolume(), ShouldEqual, 10)
+		})
+
+		Convey("Should move away from the touch in place, behind the new level's orders", func() {
+			resting := entity.NewOrder(entity.ASK_ORDER, 1)
+			ob.PlaceLimitOrder(102, resting)
+			sequence := first.ArrivalSequence
+
+			matches, err := ob.ReplaceOrder(first.ID, 102, 0)
+
+			So(err, ShouldBeNil)
+			So(matches, ShouldBeEmpty)
+			So(first.ArrivalSequence, ShouldBeGreaterThan, sequence)
+			So(ob.Asks()[1].Orders.All(), ShouldResemble, entity.Orders{resting, first})
+			indexed, _ := ob.LookupOrder(first.ID)
+			So(indexed, ShouldEqual, first)
+		})
+
+		Convey("Should match when the new price crosses", func() {
+			matches, err := ob.ReplaceOrder(first.ID, 98, 0)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 1)
+			So(first.IsFilled(), ShouldBeTrue)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Should leave the order untouched when rejected", func() {
+			first.PostOnly = true
+
+			_, err := ob.ReplaceOrder(first.ID, 97, 0)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonPostOnlyWouldCross)
+			So(first.Status, ShouldEqual, entity.OrderStatusNew)
+			So(first.Limit.Price, ShouldEqual, 100)
+		})
+
+		Convey("Should leave the order untouched when its minimum fill size can't be met", func() {
+			first.MinFillSize = 5
+
+			_, err := ob.ReplaceOrder(first.ID, 98, 3)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(first.Status, ShouldEqual, entity.OrderStatusNew)
+			So(first.Size, ShouldEqual, 5)
+			So(ob.Asks()[0].Orders.All(), ShouldResemble, entity.Orders{first, second})
+			indexed, _ := ob.LookupOrder(first.ID)
+			So(indexed, ShouldEqual, first)
+			So(ob.BidTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("Should not find orders that aren't resting", func() {
+			_, err := ob.ReplaceOrder(12345678, 100, 1)
+
+			So(err, ShouldEqual, entity.ErrNotFound)
+		})
+	})
+}
+
+func TestCancelAll(t *testing.T) {
+	Convey("When cancelling all orders", t, func() {
+		ob := entity.NewOrderBook("test")
+		place := func(owner string, placement entity.OrderPlacement, price float64) *entity.Order {
+			order := entity.NewOrder(placement, 1)
+			order.Owner = owner
+			ob.PlaceLimitOrder(price, order)
+			return order
+		}
+		aliceBid := place("alice", entity.BID_ORDER, 99)
+		aliceAsk := place("alice", entity.ASK_ORDER, 101)
+		bobAsk := place("bob", entity.ASK_ORDER, 101)
+		aliceStop := entity.NewOrder(entity.BID_ORDER, 1)
+		aliceStop.Owner = "alice"
+		ob.PlaceStopOrder(110, aliceStop)
+
+		Convey("Should cancel only the owner's resting and stop orders", func() {
+			cancelled := ob.CancelAll("alice", entity.CancelReasonUserRequested)
+
+			So(cancelled, ShouldResemble, entity.Orders{aliceBid, aliceAsk, aliceStop})
+			So(ob.OrderCount(), ShouldEqual, 1)
+			So(bobAsk.Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.StopOrders(), ShouldBeEmpty)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Should cancel everyone's orders without an owner", func() {
+			cancelled := ob.CancelAll("", entity.CancelReasonAdmin)
+
+			So(cancelled, ShouldHaveLength, 4)
+			So(ob.OrderCount(), ShouldEqual, 0)
+			So(bobAsk.CancelReason, ShouldEqual, entity.CancelReasonAdmin)
+		})
+	})
+}
+
+func TestMinFillSize(t *testing.T) {
+	Convey("When an order has a minimum fill size", t, func() {
+		ob := entity.NewOrderBook("test")
+		dust := entity.NewOrder(entity.ASK_ORDER, 1)
+		ob.PlaceLimitOrder(100, dust)
+		block := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(100, block)
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 2))
+		ob.PlaceLimitOrder(102, entity.NewOrder(entity.ASK_ORDER, 10))
+
+		Convey("Should skip executions below the floor, even across levels", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 15)
+			order.MinFillSize = 5
+
+			matches, err := ob.PlaceLimitOrder(102, order)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+			So(matches[0].Ask, ShouldEqual, block)
+			So(matches[1].Price, ShouldEqual, 102)
+			So(matches[1].SizeFilled, ShouldEqual, 5)
+			So(dust.Status, ShouldEqual, entity.OrderStatusNew)
+			So(order.IsFilled(), ShouldBeTrue)
+			So(ob.AskTotalVolume(), ShouldEqual, 8)
+		})
+
+		Convey("Should rest once the remainder falls below the floor", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 13)
+			order.MinFillSize = 5
+
+			ob.Cancel(dust.ID)
+			ob.PlaceLimitOrder(100, order)
+
+			So(order.FilledSize, ShouldEqual, 10)
+			So(order.Status, ShouldEqual, entity.OrderStatusPartiallyFilled)
+			So(order.Limit.Price, ShouldEqual, 100)
+		})
+
+		Convey("Should cancel a remainder that would rest crossing the skipped orders", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 13)
+			order.MinFillSize = 5
+
+			ob.PlaceLimitOrder(101, order)
+
+			So(order.FilledSize, ShouldEqual, 10)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonMinFillSize)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Should reject the order when the minimum can't be met", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 20)
+			order.MinFillSize = 11
+
+			_, err := ob.PlaceMarketOrder(order)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInsufficientLiquidity)
+			So(order.Status, ShouldEqual, entity.OrderStatusRejected)
+			So(ob.AskTotalVolume(), ShouldEqual, 23)
+		})
+
+		Convey("Should reject a floor above the order size", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 2)
+			order.MinFillSize = 3
+
+			_, err := ob.PlaceLimitOrder(100, order)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+		})
+	})
+}
+
+// deepBook returns a book with one ask order at each of levels price levels, two ticks apart from 10_000 up.
+func deepBook(levels int) *entity.OrderBook {
+	ob := entity.NewOrderBook("bench")
+	for i := 0; i < levels; i++ {
+		ob.PlaceLimitOrder(10_000+float64(i)*0.02, entity.NewOrder(entity.ASK_ORDER, 1))
+	}
+	return ob
+}
+
+func BenchmarkMatchBestLevel(b *testing.B) {
+	ob := deepBook(100_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Take the best level and put it back to keep the book depth constant
+		ob.PlaceLimitOrder(10_000, entity.NewOrder(entity.BID_ORDER, 1))
+		ob.PlaceLimitOrder(10_000, entity.NewOrder(entity.ASK_ORDER, 1))
+	}
+}
+
+func BenchmarkAddAndCancelLevel(b *testing.B) {
+	ob := deepBook(100_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		order := entity.NewOrder(entity.ASK_ORDER, 1)
+		// Between two existing levels deep in the book
+		ob.PlaceLimitOrder(11_000.01, order)
+		ob.CancelOrderByID(order.ID, entity.ASK_ORDER, entity.CancelReasonUserRequested)
+	}
+}
+
+func BenchmarkMinFillSizeSweep(b *testing.B) {
+	ob := deepBook(20_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Skips every level of dust to trade with a block at the back of the book
+		block := entity.NewOrder(entity.ASK_ORDER, 5)
+		ob.PlaceLimitOrder(10_500, block)
+		order := entity.NewOrder(entity.BID_ORDER, 5)
+		order.MinFillSize = 5
+		ob.PlaceLimitOrder(10_500, order)
+	}
+}
+
+// Books share the order ID sequence, so separate books must be usable from separate goroutines. Run with -race.
+func TestConcurrentBooks(t *testing.T) {
+	Convey("When separate books trade on separate goroutines", t, func() {
+		const books, orders = 4, 200
+
+		var wg sync.WaitGroup
+		ids := make([][]int64, books)
+		indexSizes := make([]int, books)
+		for i := 0; i < books; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				ob := entity.NewOrderBook(fmt.Sprintf("concurrent-%d", i))
+				for j := 0; j < orders; j++ {
+					ask := entity.NewOrder(entity.ASK_ORDER, 1)
+					ob.PlaceLimitOrder(100, ask)
+					ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+					ids[i] = append(ids[i], ask.ID)
+				}
+				indexSizes[i] = ob.OrderIndexSize()
+			}(i)
+		}
+		wg.Wait()
+
+		Convey("Should give every order a unique ID and leave nothing in the index", func() {
+			seen := map[int64]bool{}
+			for _, bookIDs := range ids {
+				for _, id := range bookIDs {
+					So(seen[id], ShouldBeFalse)
+					seen[id] = true
+				}
+			}
+			So(seen, ShouldHaveLength, books*orders)
+			So(indexSizes, ShouldResemble, make([]int, books))
+		})
 	})
 }
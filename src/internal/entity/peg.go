@@ -0,0 +1,153 @@
+package entity
+
+/*
+	Pegged orders rest at the best price of their own side plus an offset, and follow it: whenever the
+	best bid or offer moves, the book re-pegs them. The reference only counts orders that aren't pegged
+	themselves, so pegged orders never chase each other, and a pegged order is kept at least one tick
+	behind the best non-pegged opposite price so re-pegging never takes liquidity from regular orders.
+*/
+
+// BBO is the best bid and offer of a book. Prices and sizes of an empty side are zero.
+type BBO struct {
+	BidPrice float64 `json:"bid_price"`
+	BidSize  float64 `json:"bid_size"`
+	AskPrice float64 `json:"ask_price"`
+	AskSize  float64 `json:"ask_size"`
+}
+
+// BBO returns the current best bid and offer of the book.
+func (ob *OrderBook) BBO() BBO {
+	var bbo BBO
+	if best := ob.bestLimit(BID_ORDER); best != nil {
+		bbo.BidPrice, bbo.BidSize = best.Price, best.TotalVolume
+	}
+	if best := ob.bestLimit(ASK_ORDER); best != nil {
+		bbo.AskPrice, bbo.AskSize = best.Price, best.TotalVolume
+	}
+	return bbo
+}
+
+// PlacePeggedOrder places a limit order at the best non-pegged price of its side plus offset, which is
+// negative to rest behind the touch, and keeps it there as the BBO moves. It's rejected when its side has
+// no price to peg to. Replacing the order's price unpegs it.
+func (ob *OrderBook) PlacePeggedOrder(offset float64, order *Order) ([]Match, error) {
+	order.Pegged = true
+	order.PegOffset = offset
+
+	price, ok := ob.pegPrice(order)
+	if !ok {
+		ob.accept(order)
+		return nil, reject(order, RejectReasonInvalidOrder, "PlacePeggedOrder: no %s price to peg to", order.OrderPlacement)
+	}
+
+	ob.pegged = append(ob.pegged, order)
+	return ob.PlaceLimitOrder(price, order)
+}
+
+// notifyBBO re-pegs the pegged orders, notifies the level changes and notifies OnBBOChange once the BBO
+// moved since the last call. Re-pegging can move the BBO again, so it repeats until it settles.
+// It does nothing while a sweep is filling a level, whose caller notifies once the sweep completes.
+func (ob *OrderBook) notifyBBO() {
+	if ob.sweeping || ob.notifyingBBO {
+		return
+	}
+	ob.notifyingBBO = true
+	defer func() { ob.notifyingBBO = false }()
+
+	previous := ob.bbo
+	for bbo := ob.BBO(); bbo != ob.bbo; bbo = ob.BBO() {
+		ob.bbo = bbo
+		ob.repeg()
+	}
+	ob.notifyLevels()
+
+	if ob.bbo != previous && ob.OnBBOChange != nil {
+		ob.OnBBOChange(ob.bbo)
+	}
+}
+
+// repeg moves every resting pegged order whose peg price changed to the back of the queue at the new price.
+// A moved order that crosses pegged orders of the other side matches them like a new limit order.
+func (ob *OrderBook) repeg() {
+	// Pegged orders stay put while the market is halted
+	if ob.isHalted() {
+		return
+	}
+	live := ob.pegged[:0]
+	for _, order := range ob.pegged {
+		// Orders that left the book or were unpegged are dropped lazily
+		if order.Status.IsTerminal() || !order.Pegged {
+			continue
+		}
+		live = append(live, order)
+
+		limit := order.Limit
+		if limit == nil {
+			continue
+		}
+		price, ok := ob.pegPrice(order)
+		if !ok || ob.Config.ToTicks(price) == ob.Config.ToTicks(limit.Price) {
+			continue
+		}
+		if best := ob.bestLimit(order.OrderPlacement.Opposite()); order.PostOnly && best != nil && ob.crosses(order.OrderPlacement, price, best.Price) {
+			continue
+		}
+
+		limit.DeleteOrder(order)
+		if limit.IsEmpty() {
+			ob.deleteLimit(order.OrderPlacement, limit)
+		}
+		ob.mutationSequence++
+		ob.arrivalSequence++
+		order.ArrivalSequence = ob.arrivalSequence
+
+		_, depthReached := ob.sweep(order, price)
+		ob.restRemainder(price, order, depthReached)
+	}
+	for i := len(live); i < len(ob.pegged); i++ {
+		ob.pegged[i] = nil
+	}
+	ob.pegged = live
+
+	ob.triggerStops()
+	ob.springBrackets()
+}
+
+// pegPrice returns the price the pegged order should rest at, or false if its side has no non-pegged
+// order to peg to or the price isn't positive.
+func (ob *OrderBook) pegPrice(order *Order) (float64, bool) {
+	reference, ok := ob.pegReference(order.OrderPlacement)
+	if !ok {
+		return 0, false
+	}
+
+	ticks := ob.Config.ToTicks(reference + order.PegOffset)
+	if opposite, ok := ob.pegReference(order.OrderPlacement.Opposite()); ok {
+		oppositeTicks := ob.Config.ToTicks(opposite)
+		if order.OrderPlacement == BID_ORDER {
+			ticks = min(ticks, oppositeTicks-1)
+		} else {
+			ticks = max(ticks, oppositeTicks+1)
+		}
+	}
+
+	if ticks <= 0 {
+		return 0, false
+	}
+	return ob.Config.FromTicks(ticks), true
+}
+
+// pegReference returns the best price of the side among orders that aren't pegged.
+func (ob *OrderBook) pegReference(side OrderPlacement) (float64, bool) {
+	for i := 0; ; i++ {
+		limit := ob.levelAt(side, i)
+		if limit == nil {
+			return 0, false
+		}
+		for _, order := range limit.Orders.All() {
+			if !order.Pegged {
+				return limit.Price, true
+			}
+		}
+	}
+}
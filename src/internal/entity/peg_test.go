@@ -0,0 +1,107 @@
+package entity_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPeggedOrder(t *testing.T) {
+	Convey("When placing a pegged bid one tick behind the best bid", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(105, entity.NewOrder(entity.ASK_ORDER, 10))
+		best := entity.NewOrder(entity.BID_ORDER, 5)
+		ob.PlaceLimitOrder(100, best)
+
+		bbos := []entity.BBO{}
+		ob.OnBBOChange = func(bbo entity.BBO) {
+			bbos = append(bbos, bbo)
+		}
+
+		pegged := entity.NewOrder(entity.BID_ORDER, 2)
+		_, err := ob.PlacePeggedOrder(-0.01, pegged)
+		So(err, ShouldBeNil)
+
+		Convey("Should rest at the offset from the best bid", func() {
+			So(pegged.Limit.Price, ShouldEqual, 99.99)
+			So(bbos, ShouldBeEmpty)
+		})
+
+		Convey("Should follow the best bid up", func() {
+			ob.PlaceLimitOrder(101, entity.NewOrder(entity.BID_ORDER, 1))
+
+			So(pegged.Limit.Price, ShouldEqual, 100.99)
+			So(bbos, ShouldResemble, []entity.BBO{{BidPrice: 101, BidSize: 1, AskPrice: 105, AskSize: 10}})
+		})
+
+		Convey("Should follow the best bid down when it leaves", func() {
+			So(ob.Cancel(best.ID), ShouldBeNil)
+			So(pegged.Limit.Price, ShouldEqual, 99.99)
+
+			ob.PlaceLimitOrder(98, entity.NewOrder(entity.BID_ORDER, 1))
+			So(pegged.Limit.Price, ShouldEqual, 99.99)
+
+			ob.PlaceLimitOrder(90, entity.NewOrder(entity.BID_ORDER, 1))
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 2))
+			So(pegged.Status, ShouldEqual, entity.OrderStatusFilled)
+		})
+
+		Convey("Should stay put when nothing is left to peg to", func() {
+			So(ob.Cancel(best.ID), ShouldBeNil)
+
+			So(pegged.Limit.Price, ShouldEqual, 99.99)
+		})
+
+		Convey("Should unpeg when its price is replaced", func() {
+			_, err := ob.ReplaceOrder(pegged.ID, 95, 0)
+			So(err, ShouldBeNil)
+
+			ob.PlaceLimitOrder(101, entity.NewOrder(entity.BID_ORDER, 1))
+			So(pegged.Pegged, ShouldBeFalse)
+			So(pegged.Limit.Price, ShouldEqual, 95)
+		})
+
+		Convey("Should keep pegging across snapshots", func() {
+			var buf bytes.Buffer
+			So(entity.EncodeSnapshot(&buf, ob.Snapshot()), ShouldBeNil)
+			snapshot, err := entity.DecodeSnapshot(&buf)
+			So(err, ShouldBeNil)
+
+			restored := entity.RestoreOrderBook(snapshot, entity.DefaultMarketConfig)
+			restored.PlaceLimitOrder(102, entity.NewOrder(entity.BID_ORDER, 1))
+
+			bids := restored.Bids()
+			So(bids[1].Price, ShouldEqual, 101.99)
+			So(bids[1].Orders.Front().ID, ShouldEqual, pegged.ID)
+		})
+	})
+
+	Convey("When a pegged order's peg price would cross the other side", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 10))
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.BID_ORDER, 5))
+
+		pegged := entity.NewOrder(entity.BID_ORDER, 2)
+		matches, err := ob.PlacePeggedOrder(5, pegged)
+		So(err, ShouldBeNil)
+
+		Convey("Should rest one tick behind the best ask instead of taking it", func() {
+			So(matches, ShouldBeEmpty)
+			So(pegged.Limit.Price, ShouldEqual, 100.99)
+		})
+	})
+
+	Convey("When placing a pegged order on a side with nothing to peg to", t, func() {
+		ob := entity.NewOrderBook("test")
+		pegged := entity.NewOrder(entity.ASK_ORDER, 2)
+		_, err := ob.PlacePeggedOrder(0, pegged)
+
+		Convey("Should reject it", func() {
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(pegged.Status, ShouldEqual, entity.OrderStatusRejected)
+		})
+	})
+}
@@ -0,0 +1,146 @@
+package entity
+
+const maxLevelHeight = 32
+
+// priceLevels holds the levels of one side of a book in a skip list keyed by price in ticks, best price
+// first: ascending for asks, descending for bids. Inserting and removing a level is O(log n), the best level
+// is the first node and walking the list visits the levels already ordered.
+type priceLevels struct {
+	descending bool
+	head       levelNode
+	height     int
+	length     int
+	seed       uint64
+}
+
+type levelNode struct {
+	ticks int64
+	limit *Limit
+	next  []*levelNode
+}
+
+func newPriceLevels(descending bool) *priceLevels {
+	return &priceLevels{
+		descending: descending,
+		head:       levelNode{next: make([]*levelNode, maxLevelHeight)},
+		height:     1,
+		seed:       0x9e3779b97f4a7c15,
+	}
+}
+
+// before reports whether a level at ticks a is better than one at ticks b.
+func (s *priceLevels) before(a, b int64) bool {
+	if s.descending {
+		return a > b
+	}
+	return a < b
+}
+
+// insert adds the level at ticks, which must not be in the list yet.
+func (s *priceLevels) insert(ticks int64, limit *Limit) {
+	update := s.predecessors(ticks)
+
+	height := s.randomHeight()
+	for i := s.height; i < height; i++ {
+		update[i] = &s.head
+	}
+	s.height = max(s.height, height)
+
+	node := &levelNode{ticks: ticks, limit: limit, next: make([]*levelNode, height)}
+	for i := 0; i < height; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	s.length++
+}
+
+// remove deletes the level at ticks, if it's in the list.
+func (s *priceLevels) remove(ticks int64) {
+	update := s.predecessors(ticks)
+	node := update[0].next[0]
+	if node == nil || node.ticks != ticks {
+		return
+	}
+
+	for i := 0; i < len(node.next); i++ {
+		update[i].next[i] = node.next[i]
+	}
+	for s.height > 1 && s.head.next[s.height-1] == nil {
+		s.height--
+	}
+	s.length--
+}
+
+// predecessors returns, for each height, the last node ordered before ticks.
+func (s *priceLevels) predecessors(ticks int64) [maxLevelHeight]*levelNode {
+	var update [maxLevelHeight]*levelNode
+	node := &s.head
+	for i := s.height - 1; i >= 0; i-- {
+		for node.next[i] != nil && s.before(node.next[i].ticks, ticks) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+	return update
+}
+
+// at returns the level at depth i from the best price, or nil if the side isn't that deep.
+func (s *priceLevels) at(i int) *Limit {
+	node := s.head.next[0]
+	for ; node != nil && i > 0; i-- {
+		node = node.next[0]
+	}
+	if node == nil {
+		return nil
+	}
+	return node.limit
+}
+
+// after returns the first level ordered after ticks, which needn't be a level of the list, or nil if there's
+// none. It's O(log n), so a caller can walk the levels while the list changes under it.
+func (s *priceLevels) after(ticks int64) *Limit {
+	node := &s.head
+	for i := s.height - 1; i >= 0; i-- {
+		for node.next[i] != nil && !s.before(ticks, node.next[i].ticks) {
+			node = node.next[i]
+		}
+	}
+	if node = node.next[0]; node == nil {
+		return nil
+	}
+	return node.limit
+}
+
+// all returns the levels best price first.
+func (s *priceLevels) all() []*Limit {
+	limits := make([]*Limit, 0, s.length)
+	for node := s.head.next[0]; node != nil; node = node.next[0] {
+		limits = append(limits, node.limit)
+	}
+	return limits
+}
+
+// walk calls fn with the levels best price first until it returns false.
+func (s *priceLevels) walk(fn func(ticks int64, limit *Limit) bool) {
+	for node := s.head.next[0]; node != nil; node = node.next[0] {
+		if !fn(node.ticks, node.limit) {
+			return
+		}
+	}
+}
+
+// randomHeight draws a node height where each extra level has a 1/4 chance, from a xorshift generator so
+// books stay deterministic.
+func (s *priceLevels) randomHeight() int {
+	height := 1
+	for height < maxLevelHeight {
+		s.seed ^= s.seed << 13
+		s.seed ^= s.seed >> 7
+		s.seed ^= s.seed << 17
+		if s.seed&3 != 0 {
+			break
+		}
+		height++
+	}
+	return height
+}
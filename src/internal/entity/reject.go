@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RejectReason is the machine-readable reason an order was refused at entry.
+type RejectReason string
+
+const (
+	RejectReasonInvalidOrder          RejectReason = "INVALID_ORDER"
+	RejectReasonInsufficientLiquidity RejectReason = "INSUFFICIENT_LIQUIDITY"
+	RejectReasonInsufficientBalance   RejectReason = "INSUFFICIENT_BALANCE"
+	RejectReasonPriceOutOfBand        RejectReason = "PRICE_OUT_OF_BAND"
+	RejectReasonMarketHalted          RejectReason = "MARKET_HALTED"
+	RejectReasonSizeTooSmall          RejectReason = "SIZE_TOO_SMALL"
+	RejectReasonRateLimited           RejectReason = "RATE_LIMITED"
+	RejectReasonRiskLimit             RejectReason = "RISK_LIMIT"
+	RejectReasonPostOnlyWouldCross    RejectReason = "POST_ONLY_WOULD_CROSS"
+	RejectReasonAuctionInProgress     RejectReason = "AUCTION_IN_PROGRESS"
+	RejectReasonInvalidTickSize       RejectReason = "INVALID_TICK_SIZE"
+	RejectReasonInvalidLotSize        RejectReason = "INVALID_LOT_SIZE"
+	RejectReasonBelowMinNotional      RejectReason = "BELOW_MIN_NOTIONAL"
+)
+
+// RejectError is returned when an order is rejected, carrying the reason alongside the message.
+type RejectError struct {
+	Reason RejectReason
+	msg    string
+}
+
+func (e *RejectError) Error() string {
+	return e.msg
+}
+
+// reject marks the order REJECTED and returns a RejectError for the reason.
+func reject(order *Order, reason RejectReason, msg string, vals ...any) error {
+	order.Transition(OrderStatusRejected)
+	return &RejectError{
+		Reason: reason,
+		msg:    fmt.Sprintf(msg, vals...),
+	}
+}
+
+// RejectReasonOf extracts the reject reason from err or any error it wraps.
+func RejectReasonOf(err error) (RejectReason, bool) {
+	var rejectErr *RejectError
+	if errors.As(err, &rejectErr) {
+		return rejectErr.Reason, true
+	}
+	return "", false
+}
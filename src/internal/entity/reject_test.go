@@ -0,0 +1,42 @@
+package entity_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRejectReasonOf(t *testing.T) {
+	Convey("When extracting reject reasons", t, func() {
+		Convey("Should find the reason through wrapped errors", func() {
+			ob := entity.NewOrderBook("test")
+			_, err := ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+
+			reason, rejected := entity.RejectReasonOf(fmt.Errorf("wrapped: %w", err))
+
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonInsufficientLiquidity)
+		})
+
+		Convey("Should reject invalid placements", func() {
+			ob := entity.NewOrderBook("test")
+			order := entity.NewOrder("SIDEWAYS", 1)
+
+			_, err := ob.PlaceLimitOrder(10, order)
+			reason, rejected := entity.RejectReasonOf(err)
+
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(order.Status, ShouldEqual, entity.OrderStatusRejected)
+		})
+
+		Convey("Should not report plain errors as rejections", func() {
+			_, rejected := entity.RejectReasonOf(errors.New("boom"))
+
+			So(rejected, ShouldBeFalse)
+		})
+	})
+}
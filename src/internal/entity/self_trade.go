@@ -0,0 +1,64 @@
+package entity
+
+// STPMode is the self-trade prevention behavior of an incoming order that would match a resting order
+// of the same owner.
+type STPMode string
+
+const (
+	// STPCancelNewest cancels the incoming order and keeps the resting one
+	STPCancelNewest STPMode = "CANCEL_NEWEST"
+	// STPCancelOldest cancels the resting order and lets the incoming one carry on matching
+	STPCancelOldest STPMode = "CANCEL_OLDEST"
+	// STPCancelBoth cancels both orders
+	STPCancelBoth STPMode = "CANCEL_BOTH"
+	// STPDecrementAndCancel reduces both orders by the smaller size, cancelling whichever drops to zero
+	STPDecrementAndCancel STPMode = "DECREMENT_AND_CANCEL"
+)
+
+// IsValid reports whether the mode is known, treating an unset one as allowing self-trades.
+func (m STPMode) IsValid() bool {
+	switch m {
+	case "", STPCancelNewest, STPCancelOldest, STPCancelBoth, STPDecrementAndCancel:
+		return true
+	}
+	return false
+}
+
+// isSelfTrade reports whether the incoming order would trade with a resting order of its own owner
+// and asked for that to be prevented.
+func isSelfTrade(resting, order *Order) bool {
+	return order.SelfTradePrevention != "" && order.Owner != "" && resting.Owner == order.Owner
+}
+
+// preventSelfTrade applies the incoming order's self-trade prevention mode instead of matching it with
+// the resting order. Resting orders it cancels are removed from the level.
+func (l *Limit) preventSelfTrade(resting, order *Order) {
+	cancelResting := func() {
+		resting.Cancel(CancelReasonSelfTradePrevention)
+		l.DeleteOrder(resting)
+	}
+
+	switch order.SelfTradePrevention {
+	case STPCancelNewest:
+		order.Cancel(CancelReasonSelfTradePrevention)
+	case STPCancelOldest:
+		cancelResting()
+	case STPCancelBoth:
+		cancelResting()
+		order.Cancel(CancelReasonSelfTradePrevention)
+	case STPDecrementAndCancel:
+		decrement := min(resting.Size, order.Size)
+		if resting.Size == decrement {
+			cancelResting()
+		} else {
+			resting.Size = subSize(resting.Size, decrement)
+			l.TotalVolume = subSize(l.TotalVolume, decrement)
+			l.changed()
+		}
+		if order.Size == decrement {
+			order.Cancel(CancelReasonSelfTradePrevention)
+		} else {
+			order.Size = subSize(order.Size, decrement)
+		}
+	}
+}
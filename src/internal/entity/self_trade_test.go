@@ -0,0 +1,99 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSelfTradePrevention(t *testing.T) {
+	Convey("When an order would match its owner's resting order", t, func() {
+		ob := entity.NewOrderBook("test")
+		own := entity.NewOrder(entity.ASK_ORDER, 5)
+		own.Owner = "alice"
+		ob.PlaceLimitOrder(100, own)
+		other := entity.NewOrder(entity.ASK_ORDER, 5)
+		other.Owner = "bob"
+		ob.PlaceLimitOrder(100, other)
+
+		incoming := func(mode entity.STPMode, size float64) *entity.Order {
+			order := entity.NewOrder(entity.BID_ORDER, size)
+			order.Owner = "alice"
+			order.SelfTradePrevention = mode
+			return order
+		}
+
+		Convey("Should trade with itself when STP is unset", func() {
+			order := incoming("", 5)
+
+			matches, _ := ob.PlaceLimitOrder(100, order)
+
+			So(matches[0].Ask, ShouldEqual, own)
+		})
+
+		Convey("Cancel newest should cancel the incoming order", func() {
+			order := incoming(entity.STPCancelNewest, 3)
+
+			matches, err := ob.PlaceLimitOrder(100, order)
+
+			So(err, ShouldBeNil)
+			So(matches, ShouldBeEmpty)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonSelfTradePrevention)
+			So(own.Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.Bids(), ShouldBeEmpty)
+		})
+
+		Convey("Cancel oldest should cancel the resting order and keep matching", func() {
+			order := incoming(entity.STPCancelOldest, 3)
+
+			matches, _ := ob.PlaceLimitOrder(100, order)
+
+			So(own.CancelReason, ShouldEqual, entity.CancelReasonSelfTradePrevention)
+			So(len(matches), ShouldEqual, 1)
+			So(matches[0].Ask, ShouldEqual, other)
+			So(order.IsFilled(), ShouldBeTrue)
+			So(ob.AskTotalVolume(), ShouldEqual, 2)
+		})
+
+		Convey("Cancel both should cancel both orders", func() {
+			order := incoming(entity.STPCancelBoth, 3)
+
+			matches, _ := ob.PlaceLimitOrder(100, order)
+
+			So(matches, ShouldBeEmpty)
+			So(order.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(own.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(ob.AskTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("Decrement and cancel should reduce the larger order and cancel the smaller one", func() {
+			order := incoming(entity.STPDecrementAndCancel, 3)
+
+			ob.PlaceLimitOrder(100, order)
+
+			So(order.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(own.Size, ShouldEqual, 2)
+			So(own.Status, ShouldEqual, entity.OrderStatusNew)
+			So(ob.AskTotalVolume(), ShouldEqual, 7)
+		})
+
+		Convey("Decrement and cancel should let a larger incoming order carry on", func() {
+			order := incoming(entity.STPDecrementAndCancel, 8)
+
+			matches, _ := ob.PlaceMarketOrder(order)
+
+			So(own.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(matches[0].Ask, ShouldEqual, other)
+			So(order.FilledSize, ShouldEqual, 3)
+			So(order.IsFilled(), ShouldBeTrue)
+		})
+
+		Convey("Should reject unknown modes", func() {
+			_, err := ob.PlaceLimitOrder(100, incoming("CANCEL_EVERYTHING", 1))
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+		})
+	})
+}
@@ -0,0 +1,30 @@
+package entity
+
+import "math"
+
+// SizeDecimals is the precision sizes are kept at. Sizes are rounded to it on entry and size arithmetic is
+// done on whole units of it, so fills never leave residual epsilon volume behind the way float64 sums do.
+const SizeDecimals = 8
+
+var sizeScale = math.Pow10(SizeDecimals)
+
+// RoundSize rounds a size to SizeDecimals, the size its order is actually kept at.
+func RoundSize(size float64) float64 {
+	return fromSizeUnits(toSizeUnits(size))
+}
+
+func toSizeUnits(size float64) int64 {
+	return int64(math.Round(size * sizeScale))
+}
+
+func fromSizeUnits(units int64) float64 {
+	return float64(units) / sizeScale
+}
+
+func addSize(a, b float64) float64 {
+	return fromSizeUnits(toSizeUnits(a) + toSizeUnits(b))
+}
+
+func subSize(a, b float64) float64 {
+	return fromSizeUnits(toSizeUnits(a) - toSizeUnits(b))
+}
@@ -0,0 +1,100 @@
+package entity_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshotEncoding(t *testing.T) {
+	Convey("When encoding and decoding a book snapshot", t, func() {
+		ob := entity.NewOrderBook("test")
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 10)
+		ob.PlaceLimitOrder(10_000, sellOrder)
+		sellOrder2 := entity.NewOrder(entity.ASK_ORDER, 5)
+		ob.PlaceLimitOrder(10_000, sellOrder2)
+		buyOrder := entity.NewOrder(entity.BID_ORDER, 7)
+		ob.PlaceLimitOrder(9_000, buyOrder)
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 4))
+
+		var buf bytes.Buffer
+		So(entity.EncodeSnapshot(&buf, ob.Snapshot()), ShouldBeNil)
+		snapshot, err := entity.DecodeSnapshot(&buf)
+
+		Convey("Should round trip every field", func() {
+			So(err, ShouldBeNil)
+			So(snapshot, ShouldResemble, ob.Snapshot())
+			So(snapshot.Version, ShouldEqual, entity.SnapshotVersion)
+			So(snapshot.Sequence, ShouldEqual, 4)
+		})
+
+		Convey("Should restore a book with the same orders and time priority", func() {
+			ob.Cancel(sellOrder.ID)
+			ob.Cancel(sellOrder2.ID)
+			ob.Cancel(buyOrder.ID)
+
+			restored := entity.RestoreOrderBook(snapshot, entity.DefaultMarketConfig)
+
+			So(restored.Snapshot(), ShouldResemble, snapshot)
+			indexed, exists := restored.LookupOrder(sellOrder.ID)
+			So(exists, ShouldBeTrue)
+			So(indexed.ID, ShouldEqual, sellOrder.ID)
+
+			matches, err := restored.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 8))
+			So(err, ShouldBeNil)
+			So(matches[0].Ask.ID, ShouldEqual, sellOrder.ID)
+			So(matches[0].SizeFilled, ShouldEqual, 6)
+			So(matches[1].Ask.ID, ShouldEqual, sellOrder2.ID)
+
+			restored.Cancel(sellOrder2.ID)
+			restored.Cancel(buyOrder.ID)
+		})
+
+		Convey("Should not reuse restored order IDs", func() {
+			restored := entity.RestoreOrderBook(snapshot, entity.DefaultMarketConfig)
+
+			So(entity.NewOrder(entity.BID_ORDER, 1).ID, ShouldBeGreaterThan, buyOrder.ID)
+
+			restored.Cancel(sellOrder.ID)
+			restored.Cancel(sellOrder2.ID)
+			restored.Cancel(buyOrder.ID)
+		})
+	})
+
+	Convey("When decoding a snapshot written by a newer version with extra fields", t, func() {
+		type futureOrderSnapshot struct {
+			ID   int64
+			Size float64
+			Tag  string
+		}
+		type futureLevelSnapshot struct {
+			Price  float64
+			Orders []futureOrderSnapshot
+		}
+		type futureBookSnapshot struct {
+			Version int
+			Market  string
+			Asks    []futureLevelSnapshot
+			Venue   string
+		}
+
+		var buf bytes.Buffer
+		gob.NewEncoder(&buf).Encode(futureBookSnapshot{
+			Version: entity.SnapshotVersion + 1,
+			Market:  "test",
+			Asks:    []futureLevelSnapshot{{Price: 10, Orders: []futureOrderSnapshot{{ID: 1, Size: 2, Tag: "x"}}}},
+			Venue:   "future",
+		})
+
+		snapshot, err := entity.DecodeSnapshot(&buf)
+
+		Convey("Should ignore unknown fields", func() {
+			So(err, ShouldBeNil)
+			So(snapshot.Market, ShouldEqual, "test")
+			So(snapshot.Asks[0].Orders[0].Size, ShouldEqual, 2)
+		})
+	})
+}
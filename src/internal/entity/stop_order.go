@@ -0,0 +1,202 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+	Stop orders rest off-book until the last traded price crosses their stop price:
+	a buy stop triggers when the last price rises to or above it, a sell stop when it falls to or below it.
+	Once triggered, a stop order is converted into a market order and a stop-limit order into a limit order
+	at its limit price.
+*/
+
+// PlaceStopOrder holds the order off-book until the stop price is crossed, then places it as a market order.
+// A stop price that is already crossed by the last trade triggers immediately.
+func (ob *OrderBook) PlaceStopOrder(stopPrice float64, order *Order) error {
+	return ob.placeStop(stopPrice, 0, order)
+}
+
+// PlaceStopLimitOrder holds the order off-book until the stop price is crossed, then places it as a limit
+// order at limitPrice.
+func (ob *OrderBook) PlaceStopLimitOrder(stopPrice, limitPrice float64, order *Order) error {
+	if limitPrice <= 0 {
+		ob.accept(order)
+		return reject(order, RejectReasonInvalidOrder, "PlaceStopLimitOrder: invalid limit price %.2f", limitPrice)
+	}
+
+	return ob.placeStop(stopPrice, limitPrice, order)
+}
+
+// placeStop holds a stop order, with a zero limitPrice, or a stop-limit order. Both are checked against the
+// market's increments up front so they don't get rejected once triggered.
+func (ob *OrderBook) placeStop(stopPrice, limitPrice float64, order *Order) error {
+	ob.accept(order)
+
+	if order.OrderPlacement != BID_ORDER && order.OrderPlacement != ASK_ORDER {
+		return reject(order, RejectReasonInvalidOrder, "placeStop: invalid order placement %q", order.OrderPlacement)
+	}
+	if stopPrice <= 0 {
+		return reject(order, RejectReasonInvalidOrder, "placeStop: invalid stop price %.2f", stopPrice)
+	}
+	if !order.TimeInForce.IsValid() {
+		return reject(order, RejectReasonInvalidOrder, "placeStop: invalid time in force %q", order.TimeInForce)
+	}
+	if err := ob.Config.CheckOrder(limitPrice, order.Size); err != nil {
+		order.Transition(OrderStatusRejected)
+		return fmt.Errorf("placeStop: %w", err)
+	}
+
+	order.LimitPrice = ob.Config.RoundToTick(limitPrice)
+	order.StopPrice = ob.Config.RoundToTick(stopPrice)
+	ob.stops.add(order)
+	ob.queueExpiry(order)
+	ob.triggerStops()
+
+	return nil
+}
+
+// LastTradePrice returns the price of the latest match, or zero if the book hasn't traded yet.
+func (ob *OrderBook) LastTradePrice() float64 {
+	return ob.lastTradePrice
+}
+
+// StopOrders returns the stop orders waiting for their trigger, in arrival order.
+func (ob *OrderBook) StopOrders() Orders {
+	return ob.stops.all()
+}
+
+// triggerStops places every stop crossed by the last trade, the earliest to trigger first.
+// Fills of triggered stops move the last price too, so it keeps going until no stop is crossed.
+//
+// It runs once the matching operation that moved the price completes rather than after each match:
+// a sweep only moves the price in one direction, so the final price crosses every stop any of its
+// matches crossed, and triggered stops don't jump ahead of the rest of the aggressing order.
+func (ob *OrderBook) triggerStops() {
+	// Stops triggered further down the call stack are handled by this loop, and an auction triggers them
+	// once it uncrosses
+	if ob.triggeringStops || ob.auction {
+		return
+	}
+	ob.triggeringStops = true
+	defer func() { ob.triggeringStops = false }()
+
+	for {
+		order := ob.stops.popTriggered(ob.lastTradePrice)
+		if order == nil {
+			return
+		}
+		ob.cancelSibling(order)
+
+		if order.LimitPrice != 0 {
+			ob.PlaceLimitOrder(order.LimitPrice, order)
+		} else {
+			ob.PlaceMarketOrder(order)
+		}
+	}
+}
+
+func (ob *OrderBook) cancelStopOrder(order *Order, reason CancelReason) error {
+	if !ob.stops.contains(order) {
+		return ErrNotFound
+	}
+	if err := order.Cancel(reason); err != nil {
+		return err
+	}
+	ob.stops.remove(order)
+	return nil
+}
+
+// stopIndex is the per-book trigger index of waiting stop orders. Each side is kept sorted by how soon
+// its stops trigger, so checking for triggered stops only looks at the front of each side:
+// buy stops by ascending stop price, sell stops by descending stop price, ties by arrival.
+type stopIndex struct {
+	buys  Orders
+	sells Orders
+}
+
+func (s *stopIndex) side(placement OrderPlacement) *Orders {
+	if placement == BID_ORDER {
+		return &s.buys
+	}
+	return &s.sells
+}
+
+func (s *stopIndex) add(order *Order) {
+	side := s.side(order.OrderPlacement)
+	i := sort.Search(len(*side), func(i int) bool {
+		return triggersBefore(order, (*side)[i])
+	})
+
+	*side = append(*side, nil)
+	copy((*side)[i+1:], (*side)[i:])
+	(*side)[i] = order
+}
+
+func (s *stopIndex) contains(order *Order) bool {
+	for _, stop := range *s.side(order.OrderPlacement) {
+		if stop == order {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stopIndex) remove(order *Order) {
+	side := s.side(order.OrderPlacement)
+	for i, stop := range *side {
+		if stop == order {
+			*side = append((*side)[:i], (*side)[i+1:]...)
+			return
+		}
+	}
+}
+
+// popTriggered removes and returns the next stop crossed by lastTradePrice, or nil if there is none.
+// When both sides have a triggered stop, the earlier arrival goes first.
+func (s *stopIndex) popTriggered(lastTradePrice float64) *Order {
+	var next *Order
+	for _, side := range []Orders{s.buys, s.sells} {
+		if len(side) == 0 || !side[0].isStopTriggered(lastTradePrice) {
+			continue
+		}
+		if next == nil || side[0].ArrivalSequence < next.ArrivalSequence {
+			next = side[0]
+		}
+	}
+
+	if next != nil {
+		s.remove(next)
+	}
+	return next
+}
+
+// all returns every waiting stop in arrival order.
+func (s *stopIndex) all() Orders {
+	orders := append(append(Orders{}, s.buys...), s.sells...)
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ArrivalSequence < orders[j].ArrivalSequence
+	})
+	return orders
+}
+
+func triggersBefore(a, b *Order) bool {
+	if a.StopPrice != b.StopPrice {
+		if a.OrderPlacement == BID_ORDER {
+			return a.StopPrice < b.StopPrice
+		}
+		return a.StopPrice > b.StopPrice
+	}
+	return a.ArrivalSequence < b.ArrivalSequence
+}
+
+func (o *Order) isStopTriggered(lastTradePrice float64) bool {
+	if lastTradePrice == 0 {
+		return false
+	}
+	if o.OrderPlacement == BID_ORDER {
+		return lastTradePrice >= o.StopPrice
+	}
+	return lastTradePrice <= o.StopPrice
+}
@@ -0,0 +1,149 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStopOrder(t *testing.T) {
+	Convey("When placing stop orders", t, func() {
+		ob := entity.NewOrderBook("test")
+		for i := 0; i < 3; i++ {
+			ob.PlaceLimitOrder(float64(100+i), entity.NewOrder(entity.ASK_ORDER, 10))
+			ob.PlaceLimitOrder(float64(99-i), entity.NewOrder(entity.BID_ORDER, 10))
+		}
+		// Establish a last trade price of 100
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+
+		buyStop := entity.NewOrder(entity.BID_ORDER, 5)
+		So(ob.PlaceStopOrder(101, buyStop), ShouldBeNil)
+		sellStop := entity.NewOrder(entity.ASK_ORDER, 5)
+		So(ob.PlaceStopOrder(98, sellStop), ShouldBeNil)
+
+		Convey("Should rest off-book until triggered", func() {
+			So(ob.StopOrders(), ShouldResemble, entity.Orders{buyStop, sellStop})
+			So(ob.AskTotalVolume(), ShouldEqual, 29)
+			So(buyStop.Status, ShouldEqual, entity.OrderStatusNew)
+		})
+
+		Convey("Should trigger a buy stop as a market order once the last price reaches it", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 10))
+
+			So(ob.LastTradePrice(), ShouldEqual, 101)
+			So(buyStop.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(ob.StopOrders(), ShouldResemble, entity.Orders{sellStop})
+			So(ob.AskTotalVolume(), ShouldEqual, 14)
+		})
+
+		Convey("Should trigger a sell stop once the last price falls to it", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 15))
+
+			So(ob.LastTradePrice(), ShouldEqual, 98)
+			So(sellStop.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(ob.BidTotalVolume(), ShouldEqual, 10)
+		})
+
+		Convey("Should cascade stops triggered by other stops", func() {
+			cascadingStop := entity.NewOrder(entity.BID_ORDER, 5)
+			ob.PlaceStopOrder(102, cascadingStop)
+
+			// Lifts 100 and most of 101, triggering buyStop which reaches 102 and triggers cascadingStop
+			ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 16))
+
+			So(buyStop.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(cascadingStop.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(ob.LastTradePrice(), ShouldEqual, 102)
+		})
+
+		Convey("Should trigger immediately when the stop price is already crossed", func() {
+			stop := entity.NewOrder(entity.BID_ORDER, 1)
+			So(ob.PlaceStopOrder(99, stop), ShouldBeNil)
+
+			So(stop.Status, ShouldEqual, entity.OrderStatusFilled)
+		})
+
+		Convey("Should cancel a waiting stop order", func() {
+			So(ob.Cancel(buyStop.ID), ShouldBeNil)
+
+			So(buyStop.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(ob.StopOrders(), ShouldResemble, entity.Orders{sellStop})
+		})
+
+		Convey("Should reject a stop without a stop price", func() {
+			stop := entity.NewOrder(entity.BID_ORDER, 1)
+			err := ob.PlaceStopOrder(0, stop)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+		})
+	})
+}
+
+func TestStopLimitOrder(t *testing.T) {
+	Convey("When placing stop-limit orders", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 10))
+		ob.PlaceLimitOrder(105, entity.NewOrder(entity.ASK_ORDER, 10))
+		ob.PlaceLimitOrder(95, entity.NewOrder(entity.BID_ORDER, 10))
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+
+		sellStopLimit := entity.NewOrder(entity.ASK_ORDER, 3)
+		So(ob.PlaceStopLimitOrder(99, 97, sellStopLimit), ShouldBeNil)
+
+		Convey("Should inject a limit order at the limit price once triggered", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 2))
+
+			So(ob.LastTradePrice(), ShouldEqual, 95)
+			So(sellStopLimit.Status, ShouldEqual, entity.OrderStatusNew)
+			So(sellStopLimit.Limit, ShouldNotBeNil)
+			So(sellStopLimit.Limit.Price, ShouldEqual, 97)
+			So(len(ob.StopOrders()), ShouldEqual, 0)
+		})
+
+		Convey("Should cancel the injected limit order like any resting order", func() {
+			ob.PlaceMarketOrder(entity.NewOrder(entity.ASK_ORDER, 2))
+
+			So(ob.Cancel(sellStopLimit.ID), ShouldBeNil)
+			So(len(ob.AskLimits), ShouldEqual, 2)
+		})
+
+		Convey("Should reject a stop-limit without a limit price", func() {
+			order := entity.NewOrder(entity.ASK_ORDER, 3)
+			err := ob.PlaceStopLimitOrder(99, 0, order)
+
+			reason, _ := entity.RejectReasonOf(err)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(order.Status, ShouldEqual, entity.OrderStatusRejected)
+		})
+	})
+
+	Convey("When several stops are crossed at once", t, func() {
+		ob := entity.NewOrderBook("test")
+		for i := 0; i < 5; i++ {
+			ob.PlaceLimitOrder(float64(100+i), entity.NewOrder(entity.ASK_ORDER, 10))
+		}
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 1))
+
+		triggered := []*entity.Order{}
+		ob.OnTransition = func(transition entity.OrderTransition) {
+			if transition.Order.StopPrice != 0 && transition.To == entity.OrderStatusFilled {
+				triggered = append(triggered, transition.Order)
+			}
+		}
+
+		stop103 := entity.NewOrder(entity.BID_ORDER, 1)
+		ob.PlaceStopOrder(103, stop103)
+		stop101 := entity.NewOrder(entity.BID_ORDER, 1)
+		ob.PlaceStopOrder(101, stop101)
+		stop102 := entity.NewOrder(entity.BID_ORDER, 1)
+		ob.PlaceStopOrder(102, stop102)
+
+		ob.PlaceMarketOrder(entity.NewOrder(entity.BID_ORDER, 35))
+
+		Convey("Should trigger them in stop price order", func() {
+			So(triggered, ShouldResemble, []*entity.Order{stop101, stop102, stop103})
+		})
+	})
+}
@@ -0,0 +1,20 @@
+package entity
+
+// TimeInForce decides how long an order stays working once it reaches the book.
+type TimeInForce string
+
+const (
+	// TimeInForceGTC rests the order until it fills or is cancelled. It's the default when unset.
+	TimeInForceGTC TimeInForce = "GTC"
+	// TimeInForceIOC fills whatever is possible immediately and cancels the remainder instead of resting it.
+	TimeInForceIOC TimeInForce = "IOC"
+)
+
+// IsValid reports whether the time in force is known, treating an unset one as GTC.
+func (t TimeInForce) IsValid() bool {
+	switch t {
+	case "", TimeInForceGTC, TimeInForceIOC:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImmediateOrCancel(t *testing.T) {
+	Convey("When placing IOC orders", t, func() {
+		ob := entity.NewOrderBook("test")
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 5))
+		ob.PlaceLimitOrder(102, entity.NewOrder(entity.ASK_ORDER, 5))
+
+		Convey("An IOC limit order should match up to its price and cancel the remainder", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 12)
+			order.TimeInForce = entity.TimeInForceIOC
+
+			matches, err := ob.PlaceLimitOrder(101, order)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 2)
+			So(order.FilledSize, ShouldEqual, 10)
+			So(order.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonIOCRemainder)
+			So(ob.BidTotalVolume(), ShouldEqual, 0)
+			So(ob.AskTotalVolume(), ShouldEqual, 5)
+		})
+
+		Convey("An IOC limit order that doesn't cross should be cancelled without matching", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 1)
+			order.TimeInForce = entity.TimeInForceIOC
+
+			matches, err := ob.PlaceLimitOrder(99, order)
+
+			So(err, ShouldBeNil)
+			So(matches, ShouldBeEmpty)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonIOCRemainder)
+			So(ob.OrderCount(), ShouldEqual, 3)
+		})
+
+		Convey("A fully filled IOC limit order should not be cancelled", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 5)
+			order.TimeInForce = entity.TimeInForceIOC
+
+			ob.PlaceLimitOrder(100, order)
+
+			So(order.Status, ShouldEqual, entity.OrderStatusFilled)
+			So(order.CancelReason, ShouldBeEmpty)
+		})
+
+		Convey("An IOC market order should fill the available volume instead of being rejected", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 20)
+			order.TimeInForce = entity.TimeInForceIOC
+
+			matches, err := ob.PlaceMarketOrder(order)
+
+			So(err, ShouldBeNil)
+			So(len(matches), ShouldEqual, 3)
+			So(order.FilledSize, ShouldEqual, 15)
+			So(order.CancelReason, ShouldEqual, entity.CancelReasonIOCRemainder)
+		})
+
+		Convey("Should reject an unknown time in force", func() {
+			order := entity.NewOrder(entity.BID_ORDER, 1)
+			order.TimeInForce = "FOREVER"
+
+			_, err := ob.PlaceLimitOrder(100, order)
+
+			reason, rejected := entity.RejectReasonOf(err)
+			So(rejected, ShouldBeTrue)
+			So(reason, ShouldEqual, entity.RejectReasonInvalidOrder)
+			So(ob.AskTotalVolume(), ShouldEqual, 15)
+		})
+	})
+}
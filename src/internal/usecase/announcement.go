@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+type AnnouncementKind string
+
+const (
+	AnnouncementKindMaintenance AnnouncementKind = "MAINTENANCE"
+	AnnouncementKindListing     AnnouncementKind = "LISTING"
+	AnnouncementKindGeneral     AnnouncementKind = "GENERAL"
+)
+
+func (k AnnouncementKind) IsValid() bool {
+	switch k {
+	case AnnouncementKindMaintenance, AnnouncementKindListing, AnnouncementKindGeneral:
+		return true
+	}
+	return false
+}
+
+// Announcement is an operational notice for trading UIs to surface, such as planned maintenance or a new
+// listing. It's shown from when it's published until EndsAt; StartsAt is when the announced event begins.
+type Announcement struct {
+	ID    int64            `json:"id"`
+	Kind  AnnouncementKind `json:"kind"`
+	Title string           `json:"title"`
+	Body  string           `json:"body,omitempty"`
+	// Markets are the markets the announcement concerns, all of them when empty
+	Markets  []string  `json:"markets,omitempty"`
+	StartsAt time.Time `json:"starts_at"`
+	// EndsAt is when the announcement is taken down. Zero keeps it up until it's removed.
+	EndsAt time.Time `json:"ends_at"`
+}
+
+// AnnouncementBoard holds the announcements admins publish, replaced as a whole on every publication. It's
+// safe for concurrent use.
+type AnnouncementBoard struct {
+	mu            sync.Mutex
+	announcements []Announcement
+	lastID        int64
+	now           func() time.Time
+}
+
+func NewAnnouncementBoard() *AnnouncementBoard {
+	return NewAnnouncementBoardWithClock(time.Now)
+}
+
+// NewAnnouncementBoardWithClock is NewAnnouncementBoard with an injectable clock, for tests.
+func NewAnnouncementBoardWithClock(now func() time.Time) *AnnouncementBoard {
+	return &AnnouncementBoard{now: now}
+}
+
+// Replace validates the announcements and puts them up in place of the current ones, returning them with
+// their IDs. An announcement already up keeps its ID and start time; the others start now unless StartsAt
+// is set. Announcements that have already ended are left out. When one is invalid, the current ones stay.
+func (b *AnnouncementBoard) Replace(announcements []Announcement) ([]Announcement, error) {
+	for i, announcement := range announcements {
+		if !announcement.Kind.IsValid() {
+			return nil, fmt.Errorf("Replace: announcement %d: invalid kind %q", i, announcement.Kind)
+		}
+		if announcement.Title == "" {
+			return nil, fmt.Errorf("Replace: announcement %d: title is required", i)
+		}
+		if !announcement.StartsAt.IsZero() && !announcement.EndsAt.IsZero() && !announcement.EndsAt.After(announcement.StartsAt) {
+			return nil, fmt.Errorf("Replace: announcement %d ends before it starts", i)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	replaced := make([]Announcement, 0, len(announcements))
+	for _, announcement := range announcements {
+		if !announcement.EndsAt.IsZero() && !announcement.EndsAt.After(now) {
+			continue
+		}
+		if current, found := b.find(announcement); found {
+			announcement.ID, announcement.StartsAt = current.ID, current.StartsAt
+		} else {
+			b.lastID++
+			announcement.ID = b.lastID
+			if announcement.StartsAt.IsZero() {
+				announcement.StartsAt = now
+			}
+		}
+		announcement.Markets = append([]string(nil), announcement.Markets...)
+		replaced = append(replaced, announcement)
+	}
+	b.announcements = replaced
+
+	published := make([]Announcement, len(replaced))
+	copy(published, replaced)
+	return published, nil
+}
+
+// find returns the announcement up that announcement republishes: the same notice, starting at the same
+// time unless announcement starts whenever it's published.
+func (b *AnnouncementBoard) find(announcement Announcement) (Announcement, bool) {
+	for _, current := range b.announcements {
+		if current.Kind == announcement.Kind && current.Title == announcement.Title && current.Body == announcement.Body &&
+			slices.Equal(current.Markets, announcement.Markets) && current.EndsAt.Equal(announcement.EndsAt) &&
+			(announcement.StartsAt.IsZero() || current.StartsAt.Equal(announcement.StartsAt)) {
+			return current, true
+		}
+	}
+	return Announcement{}, false
+}
+
+// Current returns the announcements that haven't ended, soonest to start first. Ended ones are dropped.
+func (b *AnnouncementBoard) Current() []Announcement {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	live := b.announcements[:0]
+	for _, announcement := range b.announcements {
+		if announcement.EndsAt.IsZero() || announcement.EndsAt.After(now) {
+			live = append(live, announcement)
+		}
+	}
+	for i := len(live); i < len(b.announcements); i++ {
+		b.announcements[i] = Announcement{}
+	}
+	b.announcements = live
+
+	current := make([]Announcement, len(live))
+	copy(current, live)
+	sort.SliceStable(current, func(i, j int) bool {
+		return current[i].StartsAt.Before(current[j].StartsAt)
+	})
+	return current
+}
@@ -0,0 +1,90 @@
+package usecase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAnnouncementBoard(t *testing.T) {
+	Convey("When publishing announcements", t, func() {
+		now := time.Unix(1_000_000, 0)
+		board := usecase.NewAnnouncementBoardWithClock(func() time.Time { return now })
+
+		maintenance := usecase.Announcement{
+			Kind:     usecase.AnnouncementKindMaintenance,
+			Title:    "Scheduled maintenance",
+			StartsAt: now.Add(2 * time.Hour),
+			EndsAt:   now.Add(3 * time.Hour),
+		}
+		listing := usecase.Announcement{
+			Kind:    usecase.AnnouncementKindListing,
+			Title:   "ETH/USDT is listed",
+			Markets: []string{"ETHUSDT"},
+		}
+		published, err := board.Replace([]usecase.Announcement{maintenance, listing})
+		So(err, ShouldBeNil)
+
+		Convey("Should assign IDs and start them now by default", func() {
+			So(published[0].ID, ShouldNotEqual, published[1].ID)
+			So(published[1].StartsAt, ShouldEqual, now)
+		})
+
+		Convey("Should list them soonest to start first", func() {
+			current := board.Current()
+			So(len(current), ShouldEqual, 2)
+			So(current[0].ID, ShouldEqual, published[1].ID)
+			So(current[1].ID, ShouldEqual, published[0].ID)
+		})
+
+		Convey("Should drop them once they end", func() {
+			now = now.Add(3 * time.Hour)
+			current := board.Current()
+			So(len(current), ShouldEqual, 1)
+			So(current[0].ID, ShouldEqual, published[1].ID)
+		})
+
+		Convey("Should keep the IDs and start times of the ones republished", func() {
+			now = now.Add(time.Minute)
+			general := usecase.Announcement{Kind: usecase.AnnouncementKindGeneral, Title: "Fees are lowered"}
+			republished, err := board.Replace([]usecase.Announcement{listing, general})
+			So(err, ShouldBeNil)
+
+			So(republished[0].ID, ShouldEqual, published[1].ID)
+			So(republished[0].StartsAt, ShouldEqual, published[1].StartsAt)
+			So(republished[1].ID, ShouldBeGreaterThan, published[1].ID)
+			So(republished[1].StartsAt, ShouldEqual, now)
+			So(board.Current(), ShouldResemble, republished)
+		})
+
+		Convey("Should take down the ones left out", func() {
+			_, err := board.Replace(nil)
+			So(err, ShouldBeNil)
+			So(board.Current(), ShouldBeEmpty)
+		})
+
+		Convey("Should leave out the ones that already ended", func() {
+			republished, err := board.Replace([]usecase.Announcement{listing, {
+				Kind:   usecase.AnnouncementKindGeneral,
+				Title:  "Too late",
+				EndsAt: now.Add(-time.Minute),
+			}})
+			So(err, ShouldBeNil)
+			So(republished, ShouldHaveLength, 1)
+		})
+
+		Convey("Should reject invalid announcements and keep the current ones", func() {
+			for _, announcement := range []usecase.Announcement{
+				{Kind: "OUTAGE", Title: "Down"},
+				{Kind: usecase.AnnouncementKindGeneral},
+				{Kind: usecase.AnnouncementKindGeneral, Title: "Backwards", StartsAt: now.Add(time.Hour), EndsAt: now},
+			} {
+				_, err := board.Replace([]usecase.Announcement{listing, announcement})
+				So(err, ShouldNotBeNil)
+			}
+			So(len(board.Current()), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+// AuctionWindow is a daily call auction, from Start to End after midnight UTC. A window ending before it
+// starts runs past midnight.
+type AuctionWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains reports whether t falls in the window.
+func (w AuctionWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	offset := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// AuctionScheduler runs a background goroutine that holds the call auctions of one book in its daily
+// windows: the book goes into an auction when a window opens and is uncrossed when it closes. Auctions it
+// didn't start are left alone. The book isn't safe for concurrent use, so every run holds the lock guarding
+// it.
+type AuctionScheduler struct {
+	Book     *entity.OrderBook
+	Windows  []AuctionWindow
+	Interval time.Duration
+
+	// OnUncross, when set, is called with the auction's matches while the lock is still held.
+	OnUncross func([]entity.Match)
+	// Log, when set, gets the START_AUCTION and UNCROSS commands, so Replay holds the auctions too
+	Log *CommandLog
+
+	// started is set while the book is in an auction the scheduler started
+	started bool
+
+	lock sync.Locker
+	now  func() time.Time
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewAuctionScheduler(book *entity.OrderBook, windows []AuctionWindow, interval time.Duration, lock sync.Locker) *AuctionScheduler {
+	return NewAuctionSchedulerWithClock(book, windows, interval, lock, time.Now)
+}
+
+// NewAuctionSchedulerWithClock is NewAuctionScheduler with an injectable clock, for tests.
+func NewAuctionSchedulerWithClock(book *entity.OrderBook, windows []AuctionWindow, interval time.Duration, lock sync.Locker, now func() time.Time) *AuctionScheduler {
+	return &AuctionScheduler{
+		Book:     book,
+		Windows:  windows,
+		Interval: interval,
+		lock:     lock,
+		now:      now,
+	}
+}
+
+// Start runs the scheduler every Interval until Stop is called.
+func (s *AuctionScheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Run()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for a running run to finish.
+func (s *AuctionScheduler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Run starts an auction when the clock is in a window and the book isn't in one, or uncrosses the auction
+// it started once the clock has left its window. It returns the matches of the uncross.
+func (s *AuctionScheduler) Run() []entity.Match {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	inWindow := false
+	for _, window := range s.Windows {
+		inWindow = inWindow || window.Contains(now)
+	}
+
+	switch {
+	case inWindow && !s.started && !s.Book.InAuction():
+		s.apply(Command{Type: CommandStartAuction, Time: now.UnixNano()})
+		s.started = true
+	case !inWindow && s.started:
+		s.started = false
+		// The auction may already have been uncrossed by other means
+		if !s.Book.InAuction() {
+			return nil
+		}
+		result, err := s.apply(Command{Type: CommandUncross, Time: now.UnixNano()})
+		if err == nil && s.OnUncross != nil {
+			s.OnUncross(result.Matches)
+		}
+		return result.Matches
+	}
+	return nil
+}
+
+func (s *AuctionScheduler) apply(command Command) (CommandResult, error) {
+	// Uncrossing can trigger stop orders, so the command records their IDs too
+	result, err := applyAndRecord(s.Book, &command)
+	if s.Log != nil {
+		s.Log.Append(command)
+	}
+	return result, err
+}
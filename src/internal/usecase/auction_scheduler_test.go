@@ -0,0 +1,93 @@
+package usecase_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAuctionScheduler(t *testing.T) {
+	Convey("When a market has a daily auction window", t, func() {
+		now := time.Date(2026, 1, 1, 7, 0, 0, 0, time.UTC)
+		clock := func() time.Time { return now }
+		ob := entity.NewOrderBook("test")
+		var mu sync.Mutex
+		window := usecase.AuctionWindow{Start: 7*time.Hour + 55*time.Minute, End: 8 * time.Hour}
+		scheduler := usecase.NewAuctionSchedulerWithClock(ob, []usecase.AuctionWindow{window}, time.Millisecond, &mu, clock)
+
+		Convey("Should trade continuously outside the window", func() {
+			So(scheduler.Run(), ShouldBeNil)
+			So(ob.InAuction(), ShouldBeFalse)
+		})
+
+		Convey("Should collect orders in the window and uncross them once it closes", func() {
+			now = now.Add(55 * time.Minute)
+			scheduler.Run()
+			So(ob.InAuction(), ShouldBeTrue)
+
+			ob.PlaceLimitOrder(101, entity.NewOrder(entity.BID_ORDER, 2))
+			ob.PlaceLimitOrder(100, entity.NewOrder(entity.ASK_ORDER, 2))
+			So(ob.OrderCount(), ShouldEqual, 2)
+
+			now = now.Add(4 * time.Minute)
+			So(scheduler.Run(), ShouldBeNil)
+			So(ob.InAuction(), ShouldBeTrue)
+
+			now = now.Add(time.Minute)
+			matches := scheduler.Run()
+			So(matches, ShouldHaveLength, 1)
+			So(matches[0].Price, ShouldEqual, 100.5)
+			So(ob.InAuction(), ShouldBeFalse)
+			So(ob.OrderCount(), ShouldEqual, 0)
+		})
+
+		Convey("Should leave an auction it didn't start alone", func() {
+			ob.StartAuction()
+
+			So(scheduler.Run(), ShouldBeNil)
+			So(ob.InAuction(), ShouldBeTrue)
+		})
+
+		Convey("Should run in the background until stopped", func() {
+			uncrossed := make(chan []entity.Match, 1)
+			scheduler.OnUncross = func(matches []entity.Match) { uncrossed <- matches }
+			mu.Lock()
+			now = now.Add(55 * time.Minute)
+			mu.Unlock()
+
+			scheduler.Start()
+			for {
+				mu.Lock()
+				inAuction := ob.InAuction()
+				if inAuction {
+					now = now.Add(5 * time.Minute)
+				}
+				mu.Unlock()
+				if inAuction {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			matches := <-uncrossed
+			scheduler.Stop()
+
+			So(matches, ShouldBeEmpty)
+			So(ob.InAuction(), ShouldBeFalse)
+		})
+	})
+
+	Convey("When an auction window runs past midnight", t, func() {
+		window := usecase.AuctionWindow{Start: 23*time.Hour + 55*time.Minute, End: 5 * time.Minute}
+
+		Convey("Should contain the times on both sides of midnight", func() {
+			So(window.Contains(time.Date(2026, 1, 1, 23, 58, 0, 0, time.UTC)), ShouldBeTrue)
+			So(window.Contains(time.Date(2026, 1, 2, 0, 2, 0, 0, time.UTC)), ShouldBeTrue)
+			So(window.Contains(time.Date(2026, 1, 2, 0, 5, 0, 0, time.UTC)), ShouldBeFalse)
+			So(window.Contains(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)), ShouldBeFalse)
+		})
+	})
+}
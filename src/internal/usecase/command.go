@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+type CommandType string
+
+const (
+	// CommandOpen starts a new, empty book of Market trading under Config
+	CommandOpen      CommandType = "OPEN"
+	CommandPlace     CommandType = "PLACE"
+	CommandPlaceOCO  CommandType = "PLACE_OCO"
+	CommandMassQuote CommandType = "MASS_QUOTE"
+	// CommandTWAPSlice places a child order of the TWAP order ParentID as a PLACE command would
+	CommandTWAPSlice    CommandType = "TWAP_SLICE"
+	CommandCancel       CommandType = "CANCEL"
+	CommandAmend        CommandType = "AMEND"
+	CommandCancelAll    CommandType = "CANCEL_ALL"
+	CommandExpire       CommandType = "EXPIRE"
+	CommandStartAuction CommandType = "START_AUCTION"
+	CommandUncross      CommandType = "UNCROSS"
+)
+
+// Command is an inbound request to a book. It carries everything the book would otherwise take from the
+// clock or the shared order ID sequence, so applying the same commands to a new book rebuilds the same
+// state.
+type Command struct {
+	Type CommandType `json:"type"`
+	// Time is when the command was applied, in Unix nanoseconds. The book's clock reads it while applying it.
+	Time int64 `json:"time"`
+
+	// Market and Config open a new book
+	Market string               `json:"market,omitempty"`
+	Config *entity.MarketConfig `json:"config,omitempty"`
+
+	// Order is placed as by OrderBook.Place with OrderType and Price, the stop price of stop orders. Bracket
+	// places it as the entry of a bracket and PegOffset pegs it instead.
+	Order     *entity.OrderSnapshot `json:"order,omitempty"`
+	OrderType entity.OrderType      `json:"order_type,omitempty"`
+	Bracket   *entity.Bracket       `json:"bracket,omitempty"`
+	PegOffset *float64              `json:"peg_offset,omitempty"`
+	ParentID  int64                 `json:"parent_id,omitempty"`
+
+	// Legs are the two orders of an OCO order, placed as by OrderBook.PlaceOCOOrder
+	Legs []CommandLeg `json:"legs,omitempty"`
+
+	// OrderID is the order to cancel or amend to Price and Size
+	OrderID int64   `json:"order_id,omitempty"`
+	Price   float64 `json:"price,omitempty"`
+	Size    float64 `json:"size,omitempty"`
+
+	// Owner and Reason cancel every order of the owner, or of the book when Owner is empty. Quotes replace
+	// the owner's quote set instead.
+	Owner  string              `json:"owner,omitempty"`
+	Reason entity.CancelReason `json:"reason,omitempty"`
+	Quotes []entity.Quote      `json:"quotes,omitempty"`
+
+	// OrderIDs are the IDs of the orders the book created while applying the command, such as bracket
+	// exits and quotes, in the order it created them. A replay hands them out again.
+	OrderIDs []int64 `json:"order_ids,omitempty"`
+}
+
+// CommandLeg is one leg of an OCO command.
+type CommandLeg struct {
+	Order     entity.OrderSnapshot `json:"order"`
+	OrderType entity.OrderType     `json:"order_type"`
+	// Price is the limit price of a limit leg and the stop price of a stop leg
+	Price float64 `json:"price"`
+}
+
+// CommandResult is what applying a command did to the book.
+type CommandResult struct {
+	Matches []entity.Match
+	// Orders are the orders a CANCEL_ALL cancelled, an EXPIRE expired or a MASS_QUOTE placed
+	Orders entity.Orders
+}
+
+// PlaceCommand places the order as by OrderBook.Place. The order is captured as it is now, so it must not
+// have been placed yet.
+func PlaceCommand(orderType entity.OrderType, price float64, order *entity.Order) Command {
+	snapshot := order.Snapshot()
+	return Command{Type: CommandPlace, Order: &snapshot, OrderType: orderType, Price: price}
+}
+
+// OCOCommand places the legs as by OrderBook.PlaceOCOOrder. Like PlaceCommand, it captures their orders as
+// they are now.
+func OCOCommand(first, second entity.OCOLeg) Command {
+	legs := []CommandLeg{}
+	for _, leg := range []entity.OCOLeg{first, second} {
+		legs = append(legs, CommandLeg{Order: leg.Order.Snapshot(), OrderType: leg.Type, Price: leg.Price})
+	}
+	return Command{Type: CommandPlaceOCO, Legs: legs}
+}
+
+// ApplyCommand applies the command to the book with the book's clock reading the command's time. orders are
+// the orders a PLACE, TWAP_SLICE or PLACE_OCO command places, in the command's order; without them they're
+// restored from the command.
+func ApplyCommand(book *entity.OrderBook, command Command, orders ...*entity.Order) (CommandResult, error) {
+	clock := book.Clock
+	book.Clock = func() time.Time { return time.Unix(0, command.Time) }
+	defer func() { book.Clock = clock }()
+
+	var result CommandResult
+	var err error
+	switch command.Type {
+	case CommandPlace, CommandTWAPSlice:
+		if len(orders) == 0 {
+			if command.Order == nil {
+				return CommandResult{}, fmt.Errorf("ApplyCommand: %s command without an order", command.Type)
+			}
+			orders = entity.Orders{command.Order.Restore()}
+		}
+		switch {
+		case command.Bracket != nil:
+			result.Matches, err = book.PlaceBracketOrder(command.OrderType, command.Price, orders[0], *command.Bracket)
+		case command.PegOffset != nil:
+			result.Matches, err = book.PlacePeggedOrder(*command.PegOffset, orders[0])
+		default:
+			result.Matches, err = book.Place(command.OrderType, command.Price, orders[0])
+		}
+	case CommandPlaceOCO:
+		if len(command.Legs) != 2 {
+			return CommandResult{}, errors.New("ApplyCommand: OCO command without two legs")
+		}
+		if len(orders) == 0 {
+			orders = entity.Orders{command.Legs[0].Order.Restore(), command.Legs[1].Order.Restore()}
+		}
+		result.Matches, err = book.PlaceOCOOrder(
+			entity.OCOLeg{Type: command.Legs[0].OrderType, Price: command.Legs[0].Price, Order: orders[0]},
+			entity.OCOLeg{Type: command.Legs[1].OrderType, Price: command.Legs[1].Price, Order: orders[1]},
+		)
+	case CommandMassQuote:
+		result.Orders, result.Matches, err = book.MassQuote(command.Owner, command.Quotes)
+	case CommandCancel:
+		result.Matches, err = []entity.Match{}, book.Cancel(command.OrderID)
+	case CommandAmend:
+		result.Matches, err = book.ReplaceOrder(command.OrderID, command.Price, command.Size)
+	case CommandCancelAll:
+		result.Matches, result.Orders = []entity.Match{}, book.CancelAll(command.Owner, command.Reason)
+	case CommandExpire:
+		result.Matches, result.Orders = []entity.Match{}, book.ExpireOrders(time.Unix(0, command.Time))
+	case CommandStartAuction:
+		book.StartAuction()
+		result.Matches = []entity.Match{}
+	case CommandUncross:
+		result.Matches, err = book.Uncross()
+	default:
+		return CommandResult{}, fmt.Errorf("ApplyCommand: invalid command type %q", command.Type)
+	}
+	return result, err
+}
+
+// applyAndRecord applies the command stamped with the current time, unless it has one, and records the IDs
+// of the orders the book creates meanwhile in it.
+func applyAndRecord(book *entity.OrderBook, command *Command, orders ...*entity.Order) (CommandResult, error) {
+	if command.Time == 0 {
+		command.Time = time.Now().UnixNano()
+	}
+
+	nextOrderID := book.NextOrderID
+	book.NextOrderID = func() int64 {
+		id := entity.NextOrderID()
+		command.OrderIDs = append(command.OrderIDs, id)
+		return id
+	}
+	defer func() { book.NextOrderID = nextOrderID }()
+
+	return ApplyCommand(book, *command, orders...)
+}
+
+// Replay rebuilds the book the commands were applied to, from the last command opening one. The results
+// of the commands don't stop the replay: a rejected order was rejected the first time too.
+func Replay(commands []Command) (*entity.OrderBook, error) {
+	var book *entity.OrderBook
+	for i, command := range commands {
+		if command.Type == CommandOpen {
+			if command.Config == nil {
+				return nil, fmt.Errorf("Replay: command %d opens %s without a config", i, command.Market)
+			}
+			book = entity.NewOrderBookWithConfig(command.Market, *command.Config)
+			continue
+		}
+		if book == nil {
+			return nil, fmt.Errorf("Replay: command %d is applied before a book is opened", i)
+		}
+
+		created := 0
+		book.NextOrderID = func() int64 {
+			created++
+			if created > len(command.OrderIDs) {
+				return entity.NextOrderID()
+			}
+			return command.OrderIDs[created-1]
+		}
+		ApplyCommand(book, command)
+		if created != len(command.OrderIDs) {
+			return nil, fmt.Errorf("Replay: command %d created %d orders instead of %d", i, created, len(command.OrderIDs))
+		}
+	}
+	if book == nil {
+		return nil, errors.New("Replay: no book was opened")
+	}
+	book.NextOrderID = nil
+	return book, nil
+}
+
+// CommandLog appends commands to a writer as JSON lines. It's safe for concurrent use. Once a write fails,
+// later commands are dropped, since a log with a gap can't be replayed, and Err reports the failure.
+type CommandLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewCommandLog starts a log of the commands applied to a new book of the market, opening it with config.
+func NewCommandLog(w io.Writer, market string, config entity.MarketConfig) *CommandLog {
+	log := &CommandLog{w: w}
+	log.Append(Command{Type: CommandOpen, Time: time.Now().UnixNano(), Market: market, Config: &config})
+	return log
+}
+
+// Append writes the command as one line.
+func (l *CommandLog) Append(command Command) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return l.err
+	}
+	line, err := json.Marshal(command)
+	if err != nil {
+		l.err = fmt.Errorf("CommandLog: failed to encode %s command: %w", command.Type, err)
+		return l.err
+	}
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		l.err = fmt.Errorf("CommandLog: failed to write %s command: %w", command.Type, err)
+	}
+	return l.err
+}
+
+// Err returns the error that stopped the log, if any.
+func (l *CommandLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// ReadCommands reads a log written by CommandLog.
+func ReadCommands(r io.Reader) ([]Command, error) {
+	commands := []Command{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var command Command
+		if err := json.Unmarshal(scanner.Bytes(), &command); err != nil {
+			return nil, fmt.Errorf("ReadCommands: invalid command %d: %w", len(commands), err)
+		}
+		commands = append(commands, command)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ReadCommands: %w", err)
+	}
+	return commands, nil
+}
@@ -0,0 +1,125 @@
+package usecase_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplay(t *testing.T) {
+	Convey("When replaying the command log of a book", t, func() {
+		config := entity.MarketConfig{TickSize: 0.01, PriceBandPct: 5, HaltCooldown: time.Second}
+		engine := usecase.NewMatchingEngine(entity.NewOrderBookWithConfig("test", config))
+		var log bytes.Buffer
+		engine.Log = usecase.NewCommandLog(&log, "test", config)
+		engine.Start()
+		defer engine.Stop()
+
+		now := time.Unix(1_700_000_000, 0)
+		sweeper := usecase.NewExpirySweeperWithClock(engine.Book, time.Second, engine, func() time.Time { return now })
+		sweeper.Log = engine.Log
+		scheduler := usecase.NewTWAPSchedulerWithClock(time.Second, func() time.Time { return now })
+
+		random := rand.New(rand.NewSource(1))
+		owners := []string{"alice", "bob", "carol"}
+		live := []int64{}
+		for i := 0; i < 500; i++ {
+			now = now.Add(100 * time.Millisecond)
+			placement := entity.BID_ORDER
+			if random.Intn(2) == 0 {
+				placement = entity.ASK_ORDER
+			}
+			order := entity.NewOrder(placement, float64(1+random.Intn(5)))
+			order.Timestamp = now.UnixNano()
+			order.Owner = owners[random.Intn(len(owners))]
+			price := float64(95 + random.Intn(10))
+
+			command := usecase.PlaceCommand(entity.LimitOrder, price, order)
+			command.Time = now.UnixNano()
+			switch n := random.Intn(10); {
+			case n < 2:
+				command = usecase.PlaceCommand(entity.MarketOrder, 0, order)
+				command.Time = now.UnixNano()
+			case n < 3:
+				// Expiring or cancelling a partially filled entry springs the exits
+				order.ExpiresAt = now.Add(time.Second).UnixNano()
+				bracket := entity.Bracket{TakeProfitPrice: price + 5, StopLossPrice: price - 5}
+				if placement == entity.ASK_ORDER {
+					bracket = entity.Bracket{TakeProfitPrice: price - 5, StopLossPrice: price + 5}
+				}
+				command = usecase.PlaceCommand(entity.LimitOrder, price, order)
+				command.Time = now.UnixNano()
+				command.Bracket = &bracket
+			case n < 4:
+				order.ExpiresAt = now.Add(time.Second).UnixNano()
+				command = usecase.PlaceCommand(entity.LimitOrder, price, order)
+				command.Time = now.UnixNano()
+			case n < 5 && len(live) > 0:
+				engine.Submit(usecase.Command{Type: usecase.CommandCancel, Time: now.UnixNano(), OrderID: live[random.Intn(len(live))]})
+				continue
+			case n < 6 && len(live) > 0:
+				engine.Submit(usecase.Command{Type: usecase.CommandAmend, Time: now.UnixNano(), OrderID: live[random.Intn(len(live))], Price: price})
+				continue
+			case n < 7:
+				stop := entity.NewOrder(placement, order.Size)
+				stop.Timestamp = now.UnixNano()
+				stopPrice := price - 3
+				if placement == entity.BID_ORDER {
+					stopPrice = price + 3
+				}
+				first := entity.OCOLeg{Type: entity.LimitOrder, Price: price, Order: order}
+				second := entity.OCOLeg{Type: entity.StopOrder, Price: stopPrice, Order: stop}
+				command := usecase.OCOCommand(first, second)
+				command.Time = now.UnixNano()
+				engine.Submit(command, order, stop)
+				live = append(live, order.ID)
+				continue
+			case n < 8:
+				quotes := []entity.Quote{
+					{Placement: entity.BID_ORDER, Price: price - 2, Size: 2},
+					{Placement: entity.ASK_ORDER, Price: price + 2, Size: 2},
+				}
+				engine.Submit(usecase.Command{Type: usecase.CommandMassQuote, Time: now.UnixNano(), Owner: order.Owner, Quotes: quotes})
+				continue
+			case n < 9 && i%10 == 0:
+				engine.Submit(usecase.Command{Type: usecase.CommandCancelAll, Time: now.UnixNano(), Owner: order.Owner, Reason: entity.CancelReasonUserRequested})
+				continue
+			case n < 9:
+				scheduler.Submit(&usecase.TWAPOrder{Engine: engine, Placement: placement, Owner: order.Owner, Size: 3, Slices: 3, Interval: 200 * time.Millisecond})
+			}
+			engine.Submit(command, order)
+			live = append(live, order.ID)
+			sweeper.Sweep()
+			scheduler.Run()
+		}
+
+		var original entity.BookSnapshot
+		engine.Do(func(book *entity.OrderBook) {
+			original = book.Snapshot()
+		})
+		So(engine.Log.Err(), ShouldBeNil)
+
+		commands, err := usecase.ReadCommands(&log)
+		So(err, ShouldBeNil)
+		replayed, err := usecase.Replay(commands)
+		So(err, ShouldBeNil)
+
+		Convey("Should rebuild the identical book", func() {
+			So(original.MutationSequence, ShouldBeGreaterThan, 500)
+			So(replayed.Snapshot(), ShouldResemble, original)
+		})
+	})
+
+	Convey("When replaying commands before a book is opened", t, func() {
+		_, err := usecase.Replay([]usecase.Command{{Type: usecase.CommandCancel, OrderID: 1}})
+
+		Convey("Should fail", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
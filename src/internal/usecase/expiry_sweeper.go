@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+// ExpirySweeper runs a background goroutine that expires the good-till-date orders of one book.
+// The book isn't safe for concurrent use, so every sweep holds the lock guarding it.
+type ExpirySweeper struct {
+	Book     *entity.OrderBook
+	Interval time.Duration
+
+	// OnExpire, when set, is called with the orders expired by a sweep while the lock is still held.
+	// Each expiry is also reported to the book's OnTransition as a move to EXPIRED.
+	OnExpire func(entity.Orders)
+	// Log, when set, gets a command for every sweep that expired orders, so Replay expires them too
+	Log *CommandLog
+
+	lock sync.Locker
+	now  func() time.Time
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewExpirySweeper(book *entity.OrderBook, interval time.Duration, lock sync.Locker) *ExpirySweeper {
+	return NewExpirySweeperWithClock(book, interval, lock, time.Now)
+}
+
+// NewExpirySweeperWithClock is NewExpirySweeper with an injectable clock, for tests.
+func NewExpirySweeperWithClock(book *entity.OrderBook, interval time.Duration, lock sync.Locker, now func() time.Time) *ExpirySweeper {
+	return &ExpirySweeper{
+		Book:     book,
+		Interval: interval,
+		lock:     lock,
+		now:      now,
+	}
+}
+
+// Start sweeps every Interval until Stop is called.
+func (s *ExpirySweeper) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Sweep()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sweeper and waits for a running sweep to finish.
+func (s *ExpirySweeper) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Sweep expires the orders due at the clock's current time and returns them.
+func (s *ExpirySweeper) Sweep() entity.Orders {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Expiring a partially filled bracket entry springs its exits, so the command records their IDs too
+	command := Command{Type: CommandExpire, Time: s.now().UnixNano()}
+	result, _ := applyAndRecord(s.Book, &command)
+	expired := result.Orders
+	if len(expired) > 0 && s.Log != nil {
+		s.Log.Append(command)
+	}
+	if len(expired) > 0 && s.OnExpire != nil {
+		s.OnExpire(expired)
+	}
+	return expired
+}
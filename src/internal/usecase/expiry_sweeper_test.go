@@ -0,0 +1,81 @@
+package usecase_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpirySweeper(t *testing.T) {
+	Convey("When sweeping good-till-date orders", t, func() {
+		now := time.Unix(1_000_000, 0)
+		clock := func() time.Time { return now }
+		ob := entity.NewOrderBook("test")
+		var mu sync.Mutex
+		sweeper := usecase.NewExpirySweeperWithClock(ob, time.Millisecond, &mu, clock)
+
+		transitions := []entity.OrderTransition{}
+		ob.OnTransition = func(transition entity.OrderTransition) {
+			transitions = append(transitions, transition)
+		}
+
+		soon := entity.NewOrder(entity.BID_ORDER, 1)
+		soon.ExpiresAt = now.Add(time.Minute).UnixNano()
+		ob.PlaceLimitOrder(99, soon)
+		later := entity.NewOrder(entity.ASK_ORDER, 1)
+		later.ExpiresAt = now.Add(time.Hour).UnixNano()
+		ob.PlaceLimitOrder(101, later)
+		stop := entity.NewOrder(entity.BID_ORDER, 1)
+		stop.ExpiresAt = now.Add(time.Minute).UnixNano()
+		ob.PlaceStopOrder(110, stop)
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.BID_ORDER, 1))
+
+		Convey("Should leave orders alone until they expire", func() {
+			So(sweeper.Sweep(), ShouldBeEmpty)
+			So(ob.OrderCount(), ShouldEqual, 3)
+
+			next, ok := ob.NextExpiry()
+			So(ok, ShouldBeTrue)
+			So(next, ShouldEqual, now.Add(time.Minute))
+		})
+
+		Convey("Should expire resting and stop orders once due and emit the transitions", func() {
+			now = now.Add(time.Minute)
+
+			So(sweeper.Sweep(), ShouldResemble, entity.Orders{soon, stop})
+			So(soon.Status, ShouldEqual, entity.OrderStatusExpired)
+			So(soon.CancelReason, ShouldEqual, entity.CancelReasonExpired)
+			So(stop.Status, ShouldEqual, entity.OrderStatusExpired)
+			So(ob.StopOrders(), ShouldBeEmpty)
+			So(ob.OrderCount(), ShouldEqual, 2)
+			So(transitions[len(transitions)-1].To, ShouldEqual, entity.OrderStatusExpired)
+		})
+
+		Convey("Should skip orders that left the book before expiring", func() {
+			ob.Cancel(soon.ID)
+			now = now.Add(2 * time.Hour)
+
+			So(sweeper.Sweep(), ShouldResemble, entity.Orders{stop, later})
+			So(soon.Status, ShouldEqual, entity.OrderStatusCancelled)
+			So(ob.OrderCount(), ShouldEqual, 1)
+		})
+
+		Convey("Should sweep in the background until stopped", func() {
+			expired := make(chan entity.Orders, 1)
+			sweeper.OnExpire = func(orders entity.Orders) { expired <- orders }
+			mu.Lock()
+			now = now.Add(2 * time.Hour)
+			mu.Unlock()
+
+			sweeper.Start()
+			orders := <-expired
+			sweeper.Stop()
+
+			So(orders, ShouldHaveLength, 3)
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples caps the samples kept per operation, bounding the memory of a busy window. Past it the
+// oldest samples are dropped, so reports cover the latest maxLatencySamples operations of the window.
+const maxLatencySamples = 100_000
+
+// LatencyTracker keeps how long operations took over a rolling window, to report latency percentiles
+// against SLAs. It's safe for concurrent use.
+type LatencyTracker struct {
+	// Window is the longest window reports can cover. Samples older than it are dropped.
+	Window time.Duration
+
+	mu      sync.Mutex
+	now     func() time.Time
+	samples map[string][]latencySample
+}
+
+type latencySample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+type LatencyReport struct {
+	Operation     string  `json:"operation"`
+	WindowSeconds float64 `json:"window_seconds"`
+	Count         int     `json:"count"`
+	P50Ms         float64 `json:"p50_ms"`
+	P95Ms         float64 `json:"p95_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+	MaxMs         float64 `json:"max_ms"`
+}
+
+func NewLatencyTracker(window time.Duration) *LatencyTracker {
+	return NewLatencyTrackerWithClock(window, time.Now)
+}
+
+// NewLatencyTrackerWithClock is NewLatencyTracker with an injectable clock, for tests.
+func NewLatencyTrackerWithClock(window time.Duration, now func() time.Time) *LatencyTracker {
+	return &LatencyTracker{
+		Window:  window,
+		now:     now,
+		samples: make(map[string][]latencySample),
+	}
+}
+
+// Record records that the operation just completed after taking latency.
+func (t *LatencyTracker) Record(operation string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples[operation] = append(t.samples[operation], latencySample{at: t.now(), latency: latency})
+	t.evict(operation)
+}
+
+// Report returns the latency percentiles of every operation recorded over the last window, capped at
+// Window, by operation name. Percentiles are nearest-rank, so they're latencies that actually occurred.
+func (t *LatencyTracker) Report(window time.Duration) []LatencyReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	window = min(window, t.Window)
+	windowStart := t.now().Add(-window)
+
+	reports := []LatencyReport{}
+	for operation := range t.samples {
+		t.evict(operation)
+
+		latencies := []time.Duration{}
+		for _, sample := range t.samples[operation] {
+			if !sample.at.Before(windowStart) {
+				latencies = append(latencies, sample.latency)
+			}
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		report := LatencyReport{
+			Operation:     operation,
+			WindowSeconds: window.Seconds(),
+			Count:         len(latencies),
+		}
+		if len(latencies) > 0 {
+			report.P50Ms = milliseconds(percentile(latencies, 50))
+			report.P95Ms = milliseconds(percentile(latencies, 95))
+			report.P99Ms = milliseconds(percentile(latencies, 99))
+			report.MaxMs = milliseconds(latencies[len(latencies)-1])
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Operation < reports[j].Operation })
+	return reports
+}
+
+// evict drops the operation's samples that left the window or are past maxLatencySamples. Samples are
+// recorded in time order.
+func (t *LatencyTracker) evict(operation string) {
+	windowStart := t.now().Add(-t.Window)
+	samples := t.samples[operation]
+
+	i := max(len(samples)-maxLatencySamples, 0)
+	for i < len(samples) && samples[i].at.Before(windowStart) {
+		i++
+	}
+	t.samples[operation] = samples[i:]
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,58 @@
+package usecase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	Convey("When tracking operation latencies", t, func() {
+		now := time.Unix(1_000_000, 0)
+		tracker := usecase.NewLatencyTrackerWithClock(10*time.Minute, func() time.Time { return now })
+
+		// 1ms to 100ms placements a second apart, five minutes ago
+		now = now.Add(-5 * time.Minute)
+		for i := 1; i <= 100; i++ {
+			tracker.Record("place", time.Duration(i)*time.Millisecond)
+			now = now.Add(time.Second)
+		}
+		now = now.Add(5*time.Minute - 100*time.Second)
+		tracker.Record("cancel", 2*time.Millisecond)
+
+		Convey("Should report nearest-rank percentiles per operation", func() {
+			reports := tracker.Report(10 * time.Minute)
+
+			So(len(reports), ShouldEqual, 2)
+			So(reports[0].Operation, ShouldEqual, "cancel")
+			So(reports[0].Count, ShouldEqual, 1)
+			So(reports[0].P99Ms, ShouldEqual, 2)
+
+			So(reports[1].Operation, ShouldEqual, "place")
+			So(reports[1].Count, ShouldEqual, 100)
+			So(reports[1].P50Ms, ShouldEqual, 50)
+			So(reports[1].P95Ms, ShouldEqual, 95)
+			So(reports[1].P99Ms, ShouldEqual, 99)
+			So(reports[1].MaxMs, ShouldEqual, 100)
+		})
+
+		Convey("Should only cover the requested window", func() {
+			reports := tracker.Report(time.Minute)
+
+			So(reports[1].Operation, ShouldEqual, "place")
+			So(reports[1].Count, ShouldEqual, 0)
+			So(reports[1].WindowSeconds, ShouldEqual, 60)
+		})
+
+		Convey("Should forget latencies older than the tracker's window", func() {
+			now = now.Add(7 * time.Minute)
+			reports := tracker.Report(time.Hour)
+
+			So(reports[1].WindowSeconds, ShouldEqual, 600)
+			So(reports[1].Count, ShouldEqual, 0)
+			So(reports[0].Count, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+// bookSampleInterval bounds the book samples kept to one per interval, so a busy book doesn't grow them
+// without limit: a 24h window holds at most 86,400. A book state recorded sooner after the previous sample
+// replaces it.
+const bookSampleInterval = time.Second
+
+// maxQualityTrades caps the trades kept, bounding the memory of a busy window. Past it the oldest trades are
+// dropped, so the trade count and realized volatility cover the latest maxQualityTrades trades of the window.
+const maxQualityTrades = 100_000
+
+// MarketQuality tracks market quality over a rolling window from the trades and book states it is fed:
+// realized volatility, time-weighted spread and top-of-book depth, and uptime (share of time with a
+// two-sided market).
+type MarketQuality struct {
+	Market string
+	Window time.Duration
+
+	mu      sync.Mutex
+	now     func() time.Time
+	trades  []tradePoint
+	samples []bookSample
+}
+
+type tradePoint struct {
+	at    time.Time
+	price float64
+}
+
+type bookSample struct {
+	at       time.Time
+	twoSided bool
+	spread   float64
+	topDepth float64
+}
+
+// sameTop reports whether the samples saw the same top of book.
+func (s bookSample) sameTop(other bookSample) bool {
+	return s.twoSided == other.twoSided && s.spread == other.spread && s.topDepth == other.topDepth
+}
+
+type QualityReport struct {
+	Market                string  `json:"market"`
+	WindowSeconds         float64 `json:"window_seconds"`
+	Trades                int     `json:"trades"`
+	BookSamples           int     `json:"book_samples"`
+	RealizedVolatility    float64 `json:"realized_volatility"`
+	AverageSpread         float64 `json:"average_spread"`
+	AverageTopOfBookDepth float64 `json:"average_top_of_book_depth"`
+	Uptime                float64 `json:"uptime"`
+}
+
+func NewMarketQuality(market string, window time.Duration) *MarketQuality {
+	return NewMarketQualityWithClock(market, window, time.Now)
+}
+
+// NewMarketQualityWithClock is NewMarketQuality with an injectable clock, for tests.
+func NewMarketQualityWithClock(market string, window time.Duration, now func() time.Time) *MarketQuality {
+	return &MarketQuality{
+		Market: market,
+		Window: window,
+		now:    now,
+	}
+}
+
+// RecordMatch records a trade print. It fits entity.OrderBook.OnMatch.
+func (q *MarketQuality) RecordMatch(match entity.Match) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.trades = append(q.trades, tradePoint{at: q.now(), price: match.Price})
+	q.evict()
+}
+
+// RecordBook samples the top of the book. Call it after every book mutation; each sample holds until the next.
+// A top of book unchanged since the last sample isn't sampled again.
+func (q *MarketQuality) RecordBook(ob *entity.OrderBook) {
+	sample := bookSample{}
+	if bid, ask := ob.BestBid(), ob.BestAsk(); bid != nil && ask != nil {
+		sample.twoSided = true
+		sample.spread = ask.Price - bid.Price
+		sample.topDepth = ask.TotalVolume + bid.TotalVolume
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sample.at = q.now()
+	last := len(q.samples) - 1
+	switch {
+	case last >= 0 && q.samples[last].sameTop(sample):
+		// The last sample still holds
+	case last >= 0 && sample.at.Sub(q.samples[last].at) < bookSampleInterval:
+		sample.at = q.samples[last].at
+		q.samples[last] = sample
+	default:
+		q.samples = append(q.samples, sample)
+	}
+	q.evict()
+}
+
+func (q *MarketQuality) Report() QualityReport {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.evict()
+	report := QualityReport{
+		Market:             q.Market,
+		WindowSeconds:      q.Window.Seconds(),
+		Trades:             len(q.trades),
+		BookSamples:        len(q.samples),
+		RealizedVolatility: q.realizedVolatility(),
+	}
+
+	// Time-weight each sample by how long it held within the window; time before the first sample counts as down
+	now := q.now()
+	windowStart := now.Add(-q.Window)
+	var upTime, spreadSum, depthSum float64
+	for i, sample := range q.samples {
+		from, to := sample.at, now
+		if i+1 < len(q.samples) {
+			to = q.samples[i+1].at
+		}
+		if from.Before(windowStart) {
+			from = windowStart
+		}
+		held := to.Sub(from).Seconds()
+		if !sample.twoSided || held <= 0 {
+			continue
+		}
+
+		upTime += held
+		spreadSum += sample.spread * held
+		depthSum += sample.topDepth * held
+	}
+
+	if upTime > 0 {
+		report.AverageSpread = spreadSum / upTime
+		report.AverageTopOfBookDepth = depthSum / upTime
+	}
+	if q.Window > 0 {
+		report.Uptime = upTime / q.Window.Seconds()
+	}
+
+	return report
+}
+
+// realizedVolatility is the standard deviation of log returns between consecutive trades in the window.
+func (q *MarketQuality) realizedVolatility() float64 {
+	if len(q.trades) < 3 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(q.trades)-1)
+	for i := 1; i < len(q.trades); i++ {
+		returns = append(returns, math.Log(q.trades[i].price/q.trades[i-1].price))
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	return math.Sqrt(variance)
+}
+
+// evict drops trades older than the window or past maxQualityTrades, and samples superseded before the window
+// started.
+func (q *MarketQuality) evict() {
+	windowStart := q.now().Add(-q.Window)
+
+	i := max(len(q.trades)-maxQualityTrades, 0)
+	for i < len(q.trades) && q.trades[i].at.Before(windowStart) {
+		i++
+	}
+	q.trades = q.trades[i:]
+
+	// Keep the last sample taken before the window, it still describes the book at the window start
+	i = 0
+	for i+1 < len(q.samples) && !q.samples[i+1].at.After(windowStart) {
+		i++
+	}
+	q.samples = q.samples[i:]
+}
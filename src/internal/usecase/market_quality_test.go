@@ -0,0 +1,90 @@
+package usecase_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMarketQuality(t *testing.T) {
+	Convey("When tracking market quality", t, func() {
+		now := time.Unix(1_000_000, 0)
+		clock := func() time.Time { return now }
+		q := usecase.NewMarketQualityWithClock("test", 100*time.Second, clock)
+		ob := entity.NewOrderBook("test")
+
+		// One-sided for the first 50s of the window
+		now = now.Add(-100 * time.Second)
+		ob.PlaceLimitOrder(100, entity.NewOrder(entity.BID_ORDER, 4))
+		q.RecordBook(ob)
+
+		// Spread of 2 with depth 10 for 30s
+		now = now.Add(50 * time.Second)
+		sellOrder := entity.NewOrder(entity.ASK_ORDER, 6)
+		ob.PlaceLimitOrder(102, sellOrder)
+		q.RecordBook(ob)
+
+		// Spread of 1 with depth 5 for 20s
+		now = now.Add(30 * time.Second)
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 1))
+		q.RecordBook(ob)
+		now = now.Add(20 * time.Second)
+
+		Convey("Should time-weight spread and depth over two-sided time", func() {
+			report := q.Report()
+
+			So(report.Uptime, ShouldAlmostEqual, 0.5)
+			So(report.AverageSpread, ShouldAlmostEqual, (2.0*30+1.0*20)/50)
+			So(report.AverageTopOfBookDepth, ShouldAlmostEqual, (10.0*30+5.0*20)/50)
+		})
+
+		Convey("Should compute realized volatility from trade log returns", func() {
+			for _, price := range []float64{100, 110, 99} {
+				q.RecordMatch(entity.Match{Price: price})
+			}
+
+			r1, r2 := math.Log(110.0/100), math.Log(99.0/110)
+			mean := (r1 + r2) / 2
+			expected := math.Sqrt((r1-mean)*(r1-mean) + (r2-mean)*(r2-mean))
+
+			report := q.Report()
+			So(report.Trades, ShouldEqual, 3)
+			So(report.RealizedVolatility, ShouldAlmostEqual, expected)
+		})
+
+		Convey("Should keep one book sample per second however often the book changes", func() {
+			for i := 0; i < 1000; i++ {
+				ob.PlaceLimitOrder(100, entity.NewOrder(entity.BID_ORDER, 1))
+				q.RecordBook(ob)
+				q.RecordBook(ob)
+				now = now.Add(10 * time.Millisecond)
+			}
+
+			report := q.Report()
+			So(report.BookSamples, ShouldEqual, 3+10)
+			So(report.Uptime, ShouldAlmostEqual, 0.5+10.0/100)
+		})
+
+		Convey("Should keep the latest trades past the cap", func() {
+			for i := 0; i < 100_001; i++ {
+				q.RecordMatch(entity.Match{Price: 100})
+			}
+
+			So(q.Report().Trades, ShouldEqual, 100_000)
+		})
+
+		Convey("Should forget trades and book states outside the window", func() {
+			q.RecordMatch(entity.Match{Price: 100})
+			now = now.Add(200 * time.Second)
+
+			report := q.Report()
+			So(report.Trades, ShouldEqual, 0)
+			So(report.Uptime, ShouldAlmostEqual, 1)
+			So(report.AverageSpread, ShouldAlmostEqual, 1)
+		})
+	})
+}
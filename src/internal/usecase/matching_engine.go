@@ -1 +1,166 @@
 package usecase
+
+import (
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+// commandQueueSize bounds how many commands can wait for the matching goroutine before senders block
+const commandQueueSize = 1024
+
+// MatchingEngine is the single writer of one market's book: a dedicated goroutine applies the commands sent
+// to it one at a time, in the order they arrive, so nothing else ever touches the book concurrently and no
+// lock is held around it. Matches, status changes and price level changes are emitted as sequenced events
+// from that goroutine.
+type MatchingEngine struct {
+	Book *entity.OrderBook
+
+	// OnEvent, when set, is called on the matching goroutine for every match, order status change and price
+	// level change, in the order they happen. It must not send commands to the engine, which would deadlock.
+	OnEvent func(Event)
+	// Log, when set, gets every command applied through Submit or Apply, once applied, so Replay can rebuild
+	// the book from it. Changes made to the book by other means aren't logged.
+	Log *CommandLog
+
+	commands chan func()
+	release  chan struct{}
+	sequence int64
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Event is a match, an order status change or a price level change, numbered in the order the engine
+// emitted it.
+type Event struct {
+	Sequence   int64
+	Match      *entity.Match
+	Transition *entity.OrderTransition
+	Level      *entity.LevelDelta
+}
+
+// NewMatchingEngine takes over the book. Callbacks already set on the book keep being called, ahead of
+// OnEvent.
+func NewMatchingEngine(book *entity.OrderBook) *MatchingEngine {
+	e := &MatchingEngine{
+		Book:     book,
+		commands: make(chan func(), commandQueueSize),
+	}
+
+	onMatch, onTransition, onLevelChange := book.OnMatch, book.OnTransition, book.OnLevelChange
+	book.OnMatch = func(match entity.Match) {
+		if onMatch != nil {
+			onMatch(match)
+		}
+		e.emit(Event{Match: &match})
+	}
+	book.OnTransition = func(transition entity.OrderTransition) {
+		if onTransition != nil {
+			onTransition(transition)
+		}
+		e.emit(Event{Transition: &transition})
+	}
+	book.OnLevelChange = func(delta entity.LevelDelta) {
+		if onLevelChange != nil {
+			onLevelChange(delta)
+		}
+		e.emit(Event{Level: &delta})
+	}
+	return e
+}
+
+func (e *MatchingEngine) emit(event Event) {
+	e.sequence++
+	event.Sequence = e.sequence
+	if e.OnEvent != nil {
+		e.OnEvent(event)
+	}
+}
+
+// Start runs the matching goroutine until Stop is called.
+func (e *MatchingEngine) Start() {
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+
+	go func() {
+		defer close(e.done)
+
+		for {
+			select {
+			case command := <-e.commands:
+				command()
+			case <-e.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the matching goroutine once the running command is done. Commands still queued are dropped.
+func (e *MatchingEngine) Stop() {
+	close(e.stop)
+	<-e.done
+}
+
+// Do runs fn on the matching goroutine and waits for it to return. fn has the book to itself; it must not
+// keep the book or its orders around to use them once it returns.
+func (e *MatchingEngine) Do(fn func(*entity.OrderBook)) {
+	done := make(chan struct{})
+	e.commands <- func() {
+		defer close(done)
+		fn(e.Book)
+	}
+	<-done
+}
+
+// Submit applies the command on the matching goroutine as by Apply.
+func (e *MatchingEngine) Submit(command Command, orders ...*entity.Order) (result CommandResult, err error) {
+	e.Do(func(book *entity.OrderBook) {
+		result, err = e.Apply(book, command, orders...)
+	})
+	return result, err
+}
+
+// Apply applies the command to the book as by ApplyCommand, stamped with the current time unless it has one,
+// and appends it to Log. It must only be called from a function passed to Do.
+func (e *MatchingEngine) Apply(book *entity.OrderBook, command Command, orders ...*entity.Order) (CommandResult, error) {
+	result, err := applyAndRecord(book, &command, orders...)
+	if e.Log != nil {
+		e.Log.Append(command)
+	}
+	return result, err
+}
+
+// Place places an order as by OrderBook.Place.
+func (e *MatchingEngine) Place(orderType entity.OrderType, price float64, order *entity.Order) ([]entity.Match, error) {
+	result, err := e.Submit(PlaceCommand(orderType, price, order), order)
+	return result.Matches, err
+}
+
+// Cancel cancels a resting order as by OrderBook.Cancel.
+func (e *MatchingEngine) Cancel(orderId int64) (err error) {
+	_, err = e.Submit(Command{Type: CommandCancel, OrderID: orderId})
+	return err
+}
+
+// Amend changes the price and/or size of a resting order as by OrderBook.ReplaceOrder.
+func (e *MatchingEngine) Amend(orderId int64, price, size float64) ([]entity.Match, error) {
+	result, err := e.Submit(Command{Type: CommandAmend, OrderID: orderId, Price: price, Size: size})
+	return result.Matches, err
+}
+
+// Lock parks the matching goroutine and hands the book to the caller until Unlock, for background jobs
+// written against a sync.Locker such as ExpirySweeper. Commands sent meanwhile wait.
+func (e *MatchingEngine) Lock() {
+	acquired := make(chan struct{})
+	release := make(chan struct{})
+	e.commands <- func() {
+		close(acquired)
+		<-release
+	}
+	<-acquired
+	e.release = release
+}
+
+// Unlock resumes the matching goroutine parked by Lock.
+func (e *MatchingEngine) Unlock() {
+	close(e.release)
+}
@@ -0,0 +1,99 @@
+package usecase_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMatchingEngine(t *testing.T) {
+	Convey("When sending commands to a matching engine", t, func() {
+		ob := entity.NewOrderBook("test")
+		matched := 0
+		ob.OnMatch = func(entity.Match) {
+			matched++
+		}
+		engine := usecase.NewMatchingEngine(ob)
+		events := []usecase.Event{}
+		engine.OnEvent = func(event usecase.Event) {
+			events = append(events, event)
+		}
+		engine.Start()
+		defer engine.Stop()
+
+		ask := entity.NewOrder(entity.ASK_ORDER, 2)
+		_, err := engine.Place(entity.LimitOrder, 101, ask)
+		So(err, ShouldBeNil)
+
+		Convey("Should place, amend and cancel orders", func() {
+			_, err := engine.Amend(ask.ID, 102, 3)
+			So(err, ShouldBeNil)
+			So(engine.Cancel(ask.ID), ShouldBeNil)
+			So(engine.Cancel(ask.ID), ShouldEqual, entity.ErrNotFound)
+
+			count := -1
+			engine.Do(func(book *entity.OrderBook) {
+				count = book.OrderCount()
+			})
+			So(count, ShouldEqual, 0)
+		})
+
+		Convey("Should emit matches and status changes in sequence, after the book's own callbacks", func() {
+			matches, err := engine.Place(entity.MarketOrder, 0, entity.NewOrder(entity.BID_ORDER, 2))
+			So(err, ShouldBeNil)
+			So(matches, ShouldHaveLength, 1)
+			So(matched, ShouldEqual, 1)
+
+			var match *entity.Match
+			filled := false
+			for i, event := range events {
+				So(event.Sequence, ShouldEqual, i+1)
+				if event.Match != nil {
+					match = event.Match
+				}
+				if event.Transition != nil && event.Transition.Order == ask {
+					filled = event.Transition.To == entity.OrderStatusFilled
+				}
+			}
+			So(match, ShouldNotBeNil)
+			So(match.Ask, ShouldEqual, ask)
+			So(filled, ShouldBeTrue)
+		})
+
+		Convey("Should apply concurrent commands one at a time", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 25; j++ {
+						engine.Place(entity.LimitOrder, 100, entity.NewOrder(entity.BID_ORDER, 1))
+					}
+				}()
+			}
+			wg.Wait()
+
+			volume := 0.0
+			engine.Do(func(book *entity.OrderBook) {
+				volume = book.BidTotalVolume()
+			})
+			So(volume, ShouldEqual, 200)
+		})
+
+		Convey("Should hand the book to an expiry sweeper using it as its lock", func() {
+			expiring := entity.NewOrder(entity.BID_ORDER, 1)
+			expiring.ExpiresAt = time.Now().Add(-time.Minute).UnixNano()
+			engine.Do(func(book *entity.OrderBook) {
+				book.PlaceLimitOrder(99, expiring)
+			})
+
+			sweeper := usecase.NewExpirySweeper(ob, time.Millisecond, engine)
+			So(sweeper.Sweep(), ShouldResemble, entity.Orders{expiring})
+			So(engine.Cancel(ask.ID), ShouldBeNil)
+		})
+	})
+}
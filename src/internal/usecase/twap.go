@@ -0,0 +1,316 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+type TWAPStatus string
+
+const (
+	// TWAPStatusRunning means slices are still being sent
+	TWAPStatusRunning TWAPStatus = "RUNNING"
+	// TWAPStatusCompleted means every slice was sent. Limit slices may still be resting.
+	TWAPStatusCompleted TWAPStatus = "COMPLETED"
+	// TWAPStatusFailed means the book rejected a slice, so no more are sent
+	TWAPStatusFailed TWAPStatus = "FAILED"
+)
+
+// defaultTWAPRetention is how long finished TWAP orders can still be reported before they're dropped.
+const defaultTWAPRetention = time.Hour
+
+// TWAPOrder is a parent order executed as Slices child orders sent Interval apart, evenly spreading its size
+// over time. Each slice is sized to what the parent has left to execute divided by the slices left, so size
+// a slice didn't fill is caught up by the following ones.
+type TWAPOrder struct {
+	// Engine is the matching engine of the market the slices are sent to
+	Engine    *MatchingEngine
+	Placement entity.OrderPlacement
+	Owner     string
+	Size      float64
+	// LimitPrice makes the slices limit orders resting at this price. Zero sends market slices that fill
+	// whatever liquidity is available.
+	LimitPrice float64
+	Slices     int
+	Interval   time.Duration
+
+	ID          int64
+	Status      TWAPStatus
+	SlicesSent  int
+	Children    entity.Orders
+	NextSliceAt time.Time
+	// FinishedAt is when the order stopped running, zero while it's running
+	FinishedAt time.Time
+	// RejectReason is why the book rejected the slice that failed the order
+	RejectReason entity.RejectReason
+}
+
+type TWAPReport struct {
+	ID         int64                 `json:"id"`
+	Market     string                `json:"market"`
+	Placement  entity.OrderPlacement `json:"placement"`
+	Owner      string                `json:"owner,omitempty"`
+	Size       float64               `json:"size"`
+	LimitPrice float64               `json:"limit_price,omitempty"`
+	Slices     int                   `json:"slices"`
+	SlicesSent int                   `json:"slices_sent"`
+	FilledSize float64               `json:"filled_size"`
+	// RejectedSlices counts the slices the book rejected, with the reason of the one that failed the order
+	RejectedSlices int                 `json:"rejected_slices"`
+	RejectReason   entity.RejectReason `json:"reject_reason,omitempty"`
+	Status         TWAPStatus          `json:"status"`
+	NextSliceAt    int64               `json:"next_slice_at,omitempty"`
+}
+
+// FilledSize returns how much of the parent its child orders filled so far. The children are updated by
+// the matching goroutine, so it's only safe to call from there.
+func (t *TWAPOrder) FilledSize() float64 {
+	filled := 0.0
+	for _, child := range t.Children {
+		filled = entity.RoundSize(filled + child.FilledSize)
+	}
+	return filled
+}
+
+// report summarizes the parent's progress, with NextSliceAt in Unix milliseconds. It reads the children,
+// so it runs on the matching goroutine.
+func (t *TWAPOrder) report() TWAPReport {
+	report := TWAPReport{
+		ID:           t.ID,
+		Market:       t.Engine.Book.Market,
+		Placement:    t.Placement,
+		Owner:        t.Owner,
+		Size:         t.Size,
+		LimitPrice:   t.LimitPrice,
+		Slices:       t.Slices,
+		SlicesSent:   t.SlicesSent,
+		FilledSize:   t.FilledSize(),
+		RejectReason: t.RejectReason,
+		Status:       t.Status,
+	}
+	for _, child := range t.Children {
+		if child.Status == entity.OrderStatusRejected {
+			report.RejectedSlices++
+		}
+	}
+	if t.Status == TWAPStatusRunning {
+		report.NextSliceAt = t.NextSliceAt.UnixMilli()
+	}
+	return report
+}
+
+// committedSize returns the size filled by the children plus the size still resting in live limit children.
+func (t *TWAPOrder) committedSize() float64 {
+	committed := 0.0
+	for _, child := range t.Children {
+		committed = entity.RoundSize(committed + child.FilledSize)
+		if !child.Status.IsTerminal() {
+			committed = entity.RoundSize(committed + child.Size)
+		}
+	}
+	return committed
+}
+
+// TWAPScheduler runs a background goroutine that sends the due slices of TWAP orders to their markets'
+// matching engines.
+type TWAPScheduler struct {
+	Interval time.Duration
+	// Retention is how long finished TWAP orders are kept for Report
+	Retention time.Duration
+
+	// OnSlice, when set, is called on the matching goroutine with the book and every child order sent.
+	OnSlice func(*entity.OrderBook, *TWAPOrder, *entity.Order)
+
+	// mu guards the TWAP orders; their children are only read on the matching goroutines
+	mu     sync.Mutex
+	now    func() time.Time
+	lastID int64
+	algos  map[int64]*TWAPOrder
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewTWAPScheduler creates a scheduler checking for due slices every interval, which bounds how late a slice
+// can be sent.
+func NewTWAPScheduler(interval time.Duration) *TWAPScheduler {
+	return NewTWAPSchedulerWithClock(interval, time.Now)
+}
+
+// NewTWAPSchedulerWithClock is NewTWAPScheduler with an injectable clock, for tests.
+func NewTWAPSchedulerWithClock(interval time.Duration, now func() time.Time) *TWAPScheduler {
+	return &TWAPScheduler{
+		Interval:  interval,
+		Retention: defaultTWAPRetention,
+		now:       now,
+		algos:     make(map[int64]*TWAPOrder),
+	}
+}
+
+// Submit validates the TWAP order and schedules its first slice for the next run. The parent and its even
+// slices must fit the market's increments, see MarketConfig.CheckOrder: off-tick limit prices, sizes off
+// the lot size and limit slices worth less than the minimum notional are rejected with their reason.
+func (s *TWAPScheduler) Submit(algo *TWAPOrder) error {
+	if algo.Engine == nil {
+		return errors.New("Submit: missing matching engine")
+	}
+	if algo.Placement != entity.BID_ORDER && algo.Placement != entity.ASK_ORDER {
+		return fmt.Errorf("Submit: invalid order placement %q", algo.Placement)
+	}
+	if algo.Size <= 0 || algo.LimitPrice < 0 {
+		return fmt.Errorf("Submit: invalid size %.2f or limit price %.2f", algo.Size, algo.LimitPrice)
+	}
+	if algo.Slices < 1 || algo.Interval <= 0 {
+		return fmt.Errorf("Submit: invalid %d slices every %s", algo.Slices, algo.Interval)
+	}
+	config := algo.Engine.Book.Config
+	if err := config.CheckOrder(algo.LimitPrice, algo.Size); err != nil {
+		return fmt.Errorf("Submit: %w", err)
+	}
+	if err := config.CheckOrder(algo.LimitPrice, config.RoundToLot(algo.Size/float64(algo.Slices))); err != nil {
+		return fmt.Errorf("Submit: invalid slice: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	algo.ID = s.lastID
+	algo.Status = TWAPStatusRunning
+	algo.NextSliceAt = s.now()
+	s.algos[algo.ID] = algo
+	return nil
+}
+
+// Report returns the progress of the TWAP order with the given ID, or false if there's none.
+func (s *TWAPScheduler) Report(id int64) (TWAPReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	algo, exists := s.algos[id]
+	if !exists {
+		return TWAPReport{}, false
+	}
+
+	var report TWAPReport
+	algo.Engine.Do(func(*entity.OrderBook) {
+		report = algo.report()
+	})
+	return report, true
+}
+
+// Start runs every Interval until Stop is called.
+func (s *TWAPScheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Run()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for a running run to finish.
+func (s *TWAPScheduler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Run sends the slice of every running TWAP order that is due at the clock's current time, oldest order
+// first, and returns the child orders sent. A late order sends one slice per run rather than catching up
+// in a burst.
+func (s *TWAPScheduler) Run() entity.Orders {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	due := []*TWAPOrder{}
+	for id, algo := range s.algos {
+		if algo.Status != TWAPStatusRunning && now.Sub(algo.FinishedAt) >= s.Retention {
+			delete(s.algos, id)
+			continue
+		}
+		if algo.Status == TWAPStatusRunning && !now.Before(algo.NextSliceAt) {
+			due = append(due, algo)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].ID < due[j].ID
+	})
+
+	sent := entity.Orders{}
+	for _, algo := range due {
+		if child := s.sendSlice(algo); child != nil {
+			sent = append(sent, child)
+		}
+
+		algo.NextSliceAt = algo.NextSliceAt.Add(algo.Interval)
+		if algo.NextSliceAt.Before(now) {
+			algo.NextSliceAt = now.Add(algo.Interval)
+		}
+		if algo.RejectReason != "" {
+			algo.Status = TWAPStatusFailed
+			algo.FinishedAt = now
+		} else if algo.SlicesSent == algo.Slices {
+			algo.Status = TWAPStatusCompleted
+			algo.FinishedAt = now
+		}
+	}
+	return sent
+}
+
+// sendSlice places the next child order of the TWAP order on its matching engine, or nothing if the parent
+// is already fully committed.
+func (s *TWAPScheduler) sendSlice(algo *TWAPOrder) *entity.Order {
+	slicesLeft := algo.Slices - algo.SlicesSent
+	algo.SlicesSent++
+
+	var child *entity.Order
+	algo.Engine.Do(func(book *entity.OrderBook) {
+		size := book.Config.RoundToLot((algo.Size - algo.committedSize()) / float64(slicesLeft))
+		if slicesLeft == 1 {
+			// The last slice takes what rounding the others down to whole lots left over
+			size = entity.RoundSize(algo.Size - algo.committedSize())
+		}
+		if size <= 0 {
+			return
+		}
+
+		child = entity.NewOrder(algo.Placement, size)
+		child.Owner = algo.Owner
+		algo.Children = append(algo.Children, child)
+		command := PlaceCommand(entity.LimitOrder, algo.LimitPrice, child)
+		if algo.LimitPrice <= 0 {
+			child.AllowPartialFill = true
+			command = PlaceCommand(entity.MarketOrder, 0, child)
+		}
+		command.Type = CommandTWAPSlice
+		command.Time = s.now().UnixNano()
+		command.ParentID = algo.ID
+		if _, err := algo.Engine.Apply(book, command, child); child.Status == entity.OrderStatusRejected {
+			algo.RejectReason = entity.RejectReasonInvalidOrder
+			if reason, rejected := entity.RejectReasonOf(err); rejected {
+				algo.RejectReason = reason
+			}
+		}
+
+		if s.OnSlice != nil {
+			s.OnSlice(book, algo, child)
+		}
+	})
+	return child
+}
@@ -0,0 +1,247 @@
+package usecase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+	"github.com/idzharbae/crypto-exchange/src/internal/usecase"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTWAPScheduler(t *testing.T) {
+	Convey("When running a TWAP order of market slices", t, func() {
+		now := time.Unix(1_000_000, 0)
+		clock := func() time.Time { return now }
+		ob := entity.NewOrderBook("test")
+		engine := usecase.NewMatchingEngine(ob)
+		engine.Start()
+		defer engine.Stop()
+		scheduler := usecase.NewTWAPSchedulerWithClock(time.Millisecond, clock)
+
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 2))
+		algo := &usecase.TWAPOrder{
+			Engine:    engine,
+			Placement: entity.BID_ORDER,
+			Owner:     "alice",
+			Size:      6,
+			Slices:    3,
+			Interval:  time.Minute,
+		}
+		So(scheduler.Submit(algo), ShouldBeNil)
+
+		Convey("Should send the first slice right away and the rest once due", func() {
+			sent := scheduler.Run()
+			So(sent, ShouldHaveLength, 1)
+			So(sent[0].FilledSize, ShouldEqual, 2)
+			So(sent[0].Owner, ShouldEqual, "alice")
+
+			So(scheduler.Run(), ShouldBeEmpty)
+			So(algo.NextSliceAt, ShouldEqual, now.Add(time.Minute))
+
+			now = now.Add(time.Minute)
+			ob.PlaceLimitOrder(102, entity.NewOrder(entity.ASK_ORDER, 10))
+			sent = scheduler.Run()
+			So(sent, ShouldHaveLength, 1)
+			So(sent[0].FilledSize, ShouldEqual, 2)
+			So(algo.Status, ShouldEqual, usecase.TWAPStatusRunning)
+		})
+
+		Convey("Should catch up on size an earlier slice couldn't fill", func() {
+			scheduler.Run()
+			now = now.Add(time.Minute)
+			scheduler.Run()
+
+			So(algo.Children[1].Status, ShouldEqual, entity.OrderStatusCancelled)
+
+			now = now.Add(time.Minute)
+			ob.PlaceLimitOrder(102, entity.NewOrder(entity.ASK_ORDER, 10))
+			sent := scheduler.Run()
+			So(sent[0].FilledSize, ShouldEqual, 4)
+
+			report, exists := scheduler.Report(algo.ID)
+			So(exists, ShouldBeTrue)
+			So(report.SlicesSent, ShouldEqual, 3)
+			So(report.FilledSize, ShouldEqual, 6)
+			So(report.Status, ShouldEqual, usecase.TWAPStatusCompleted)
+			So(report.NextSliceAt, ShouldEqual, 0)
+		})
+
+		Convey("Should report its progress by its ID", func() {
+			scheduler.Run()
+
+			report, exists := scheduler.Report(algo.ID)
+			So(exists, ShouldBeTrue)
+			So(report.Market, ShouldEqual, "test")
+			So(report.SlicesSent, ShouldEqual, 1)
+			So(report.FilledSize, ShouldEqual, 2)
+
+			_, exists = scheduler.Report(algo.ID + 1)
+			So(exists, ShouldBeFalse)
+		})
+
+		Convey("Should run in the background until stopped", func() {
+			sliced := make(chan *entity.Order, 1)
+			scheduler.OnSlice = func(_ *entity.OrderBook, _ *usecase.TWAPOrder, child *entity.Order) {
+				select {
+				case sliced <- child:
+				default:
+				}
+			}
+
+			scheduler.Start()
+			child := <-sliced
+			scheduler.Stop()
+
+			So(child.FilledSize, ShouldEqual, 2)
+		})
+	})
+
+	Convey("When running a TWAP order of limit slices", t, func() {
+		now := time.Unix(1_000_000, 0)
+		clock := func() time.Time { return now }
+		ob := entity.NewOrderBook("test")
+		engine := usecase.NewMatchingEngine(ob)
+		engine.Start()
+		defer engine.Stop()
+		scheduler := usecase.NewTWAPSchedulerWithClock(time.Millisecond, clock)
+
+		algo := &usecase.TWAPOrder{
+			Engine:     engine,
+			Placement:  entity.ASK_ORDER,
+			Size:       4,
+			LimitPrice: 100,
+			Slices:     2,
+			Interval:   time.Second,
+		}
+		So(scheduler.Submit(algo), ShouldBeNil)
+
+		Convey("Should count resting slices as committed", func() {
+			scheduler.Run()
+			now = now.Add(time.Second)
+			scheduler.Run()
+
+			So(ob.AskTotalVolume(), ShouldEqual, 4)
+			So(algo.Children, ShouldHaveLength, 2)
+			So(algo.Children[1].Size, ShouldEqual, 2)
+		})
+
+		Convey("Should drop it once finished for longer than the retention", func() {
+			scheduler.Run()
+			now = now.Add(time.Second)
+			scheduler.Run()
+			So(algo.Status, ShouldEqual, usecase.TWAPStatusCompleted)
+
+			now = now.Add(scheduler.Retention - time.Second)
+			scheduler.Run()
+			_, exists := scheduler.Report(algo.ID)
+			So(exists, ShouldBeTrue)
+
+			now = now.Add(time.Second)
+			scheduler.Run()
+			_, exists = scheduler.Report(algo.ID)
+			So(exists, ShouldBeFalse)
+		})
+	})
+
+	Convey("When the book rejects a slice", t, func() {
+		now := time.Unix(1_000_000, 0)
+		clock := func() time.Time { return now }
+		config := entity.DefaultMarketConfig
+		config.PriceBandPct = 10
+		engine := usecase.NewMatchingEngine(entity.RestoreOrderBook(entity.BookSnapshot{Market: "test", LastTradePrice: 100}, config))
+		engine.Start()
+		defer engine.Stop()
+		scheduler := usecase.NewTWAPSchedulerWithClock(time.Millisecond, clock)
+
+		algo := &usecase.TWAPOrder{
+			Engine:     engine,
+			Placement:  entity.BID_ORDER,
+			Size:       4,
+			LimitPrice: 120,
+			Slices:     2,
+			Interval:   time.Second,
+		}
+		So(scheduler.Submit(algo), ShouldBeNil)
+
+		Convey("Should fail the order and report the rejected slice", func() {
+			scheduler.Run()
+			now = now.Add(time.Second)
+			So(scheduler.Run(), ShouldBeEmpty)
+
+			report, _ := scheduler.Report(algo.ID)
+			So(report.Status, ShouldEqual, usecase.TWAPStatusFailed)
+			So(report.SlicesSent, ShouldEqual, 1)
+			So(report.RejectedSlices, ShouldEqual, 1)
+			So(report.RejectReason, ShouldEqual, entity.RejectReasonPriceOutOfBand)
+		})
+	})
+
+	Convey("When slice sizes don't add up exactly in binary floating point", t, func() {
+		now := time.Unix(1_000_000, 0)
+		clock := func() time.Time { return now }
+		ob := entity.NewOrderBook("test")
+		engine := usecase.NewMatchingEngine(ob)
+		engine.Start()
+		defer engine.Stop()
+		scheduler := usecase.NewTWAPSchedulerWithClock(time.Millisecond, clock)
+
+		ob.PlaceLimitOrder(101, entity.NewOrder(entity.ASK_ORDER, 1))
+		algo := &usecase.TWAPOrder{
+			Engine:    engine,
+			Placement: entity.BID_ORDER,
+			Size:      0.3,
+			Slices:    3,
+			Interval:  time.Second,
+		}
+		So(scheduler.Submit(algo), ShouldBeNil)
+
+		Convey("Should report the fills summed at the size precision", func() {
+			for i := 0; i < 3; i++ {
+				scheduler.Run()
+				now = now.Add(time.Second)
+			}
+
+			report, _ := scheduler.Report(algo.ID)
+			So(report.FilledSize, ShouldEqual, 0.3)
+			So(report.Status, ShouldEqual, usecase.TWAPStatusCompleted)
+		})
+	})
+
+	Convey("When submitting an invalid TWAP order", t, func() {
+		scheduler := usecase.NewTWAPScheduler(time.Second)
+		err := scheduler.Submit(&usecase.TWAPOrder{
+			Engine:    usecase.NewMatchingEngine(entity.NewOrderBook("test")),
+			Placement: entity.BID_ORDER,
+			Size:      1,
+			Interval:  time.Second,
+		})
+
+		Convey("Should refuse it", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("When a TWAP order doesn't fit the market's increments", t, func() {
+		scheduler := usecase.NewTWAPScheduler(time.Second)
+		engine := usecase.NewMatchingEngine(entity.NewOrderBookWithConfig("test", entity.MarketConfig{TickSize: 0.01, LotSize: 0.0001, MinNotional: 1}))
+		submit := func(size, limitPrice float64) entity.RejectReason {
+			reason, _ := entity.RejectReasonOf(scheduler.Submit(&usecase.TWAPOrder{
+				Engine:     engine,
+				Placement:  entity.BID_ORDER,
+				Size:       size,
+				LimitPrice: limitPrice,
+				Slices:     3,
+				Interval:   time.Second,
+			}))
+			return reason
+		}
+
+		Convey("Should refuse it with the reason", func() {
+			So(submit(0.00015, 2000), ShouldEqual, entity.RejectReasonInvalidLotSize)
+			So(submit(0.003, 2000.005), ShouldEqual, entity.RejectReasonInvalidTickSize)
+			So(submit(0.0012, 2000), ShouldEqual, entity.RejectReasonBelowMinNotional)
+			So(submit(0.003, 2000), ShouldBeEmpty)
+		})
+	})
+}
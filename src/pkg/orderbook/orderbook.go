@@ -0,0 +1,117 @@
+// Package orderbook exposes the exchange's matching engine for embedding in other programs,
+// without the HTTP server. It only depends on the standard library.
+//
+//	book := orderbook.NewOrderBook("ETH")
+//	book.OnMatch = func(m orderbook.Match) { ... }
+//	book.Place(orderbook.LimitOrder, 3000, orderbook.NewOrder(orderbook.ASK_ORDER, 1))
+//	matches, err := book.Place(orderbook.MarketOrder, 0, orderbook.NewOrder(orderbook.BID_ORDER, 1))
+//	book.Cancel(orderID)
+//	snapshot := book.Snapshot()
+//
+// A book isn't safe for concurrent use: serialize the calls on each book, e.g. with a mutex per book.
+// Different books can be used from different goroutines.
+package orderbook
+
+import (
+	"io"
+
+	"github.com/idzharbae/crypto-exchange/src/internal/entity"
+)
+
+type (
+	OrderBook       = entity.OrderBook
+	MarketConfig    = entity.MarketConfig
+	Order           = entity.Order
+	OrderType       = entity.OrderType
+	OrderPlacement  = entity.OrderPlacement
+	OrderStatus     = entity.OrderStatus
+	OrderTransition = entity.OrderTransition
+	CancelReason    = entity.CancelReason
+	RejectReason    = entity.RejectReason
+	RejectError     = entity.RejectError
+	Match           = entity.Match
+	BookSnapshot    = entity.BookSnapshot
+	LevelSnapshot   = entity.LevelSnapshot
+	OrderSnapshot   = entity.OrderSnapshot
+	TimeInForce     = entity.TimeInForce
+	OCOLeg          = entity.OCOLeg
+	Quote           = entity.Quote
+	STPMode         = entity.STPMode
+	BBO             = entity.BBO
+	Bracket         = entity.Bracket
+	L2Snapshot      = entity.L2Snapshot
+	L2Level         = entity.L2Level
+	L3Snapshot      = entity.L3Snapshot
+	L3Level         = entity.L3Level
+	L3Order         = entity.L3Order
+	MarketState     = entity.MarketState
+	LevelDelta      = entity.LevelDelta
+	LevelAction     = entity.LevelAction
+)
+
+const (
+	MarketOrder    = entity.MarketOrder
+	LimitOrder     = entity.LimitOrder
+	StopOrder      = entity.StopOrder
+	StopLimitOrder = entity.StopLimitOrder
+
+	BID_ORDER = entity.BID_ORDER
+	ASK_ORDER = entity.ASK_ORDER
+
+	TimeInForceGTC = entity.TimeInForceGTC
+	TimeInForceIOC = entity.TimeInForceIOC
+
+	STPCancelNewest       = entity.STPCancelNewest
+	STPCancelOldest       = entity.STPCancelOldest
+	STPCancelBoth         = entity.STPCancelBoth
+	STPDecrementAndCancel = entity.STPDecrementAndCancel
+
+	OrderStatusNew             = entity.OrderStatusNew
+	OrderStatusPartiallyFilled = entity.OrderStatusPartiallyFilled
+	OrderStatusFilled          = entity.OrderStatusFilled
+	OrderStatusCancelled       = entity.OrderStatusCancelled
+	OrderStatusExpired         = entity.OrderStatusExpired
+	OrderStatusRejected        = entity.OrderStatusRejected
+
+	LevelAdded   = entity.LevelAdded
+	LevelChanged = entity.LevelChanged
+	LevelRemoved = entity.LevelRemoved
+)
+
+var (
+	ErrNotFound = entity.ErrNotFound
+
+	DefaultMarketConfig = entity.DefaultMarketConfig
+)
+
+func NewOrderBook(market string) *OrderBook {
+	return entity.NewOrderBook(market)
+}
+
+func NewOrderBookWithConfig(market string, config MarketConfig) *OrderBook {
+	return entity.NewOrderBookWithConfig(market, config)
+}
+
+func NewOrder(orderPlacement OrderPlacement, size float64) *Order {
+	return entity.NewOrder(orderPlacement, size)
+}
+
+// RejectReasonOf extracts the reject reason from an error returned by Place.
+func RejectReasonOf(err error) (RejectReason, bool) {
+	return entity.RejectReasonOf(err)
+}
+
+// EncodeSnapshot writes the snapshot in the versioned binary snapshot format.
+func EncodeSnapshot(w io.Writer, snapshot BookSnapshot) error {
+	return entity.EncodeSnapshot(w, snapshot)
+}
+
+// DecodeSnapshot reads a snapshot written by EncodeSnapshot of this or a later version.
+func DecodeSnapshot(r io.Reader) (BookSnapshot, error) {
+	return entity.DecodeSnapshot(r)
+}
+
+// RestoreOrderBook rebuilds a book from a snapshot, keeping order IDs and time priority.
+func RestoreOrderBook(snapshot BookSnapshot, config MarketConfig) *OrderBook {
+	return entity.RestoreOrderBook(snapshot, config)
+}